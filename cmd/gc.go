@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"moments-go/storage"
+)
+
+// runGC 命令行清理文件仓库中未被引用的文件：moments-go gc [-y]
+func runGC(args []string) error {
+	flags := flag.NewFlagSet("gc", flag.ExitOnError)
+	yes := flags.Bool("y", false, "不询问，直接删除")
+	flags.Parse(args)
+
+	if pid := storage.RunningBot(); pid != 0 {
+		return fmt.Errorf("机器人正在运行（进程号 %d），请在 Telegram 中发送 /gc，或停止机器人后再运行", pid)
+	}
+
+	fmt.Println("正在查找未被引用的文件...")
+	orphans, err := storage.FindOrphans()
+	if err != nil {
+		return err
+	}
+	fmt.Print(storage.FormatOrphans(orphans, 0))
+	if len(orphans) == 0 {
+		return nil
+	}
+
+	if !*yes && !confirm(fmt.Sprintf("确认在一次提交中删除以上 %d 个文件？[y/N] ", len(orphans))) {
+		fmt.Println("已取消")
+		return nil
+	}
+
+	var paths []string
+	for _, orphan := range orphans {
+		paths = append(paths, orphan.Path)
+	}
+	deleted, err := storage.DeleteOrphans(paths)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("已删除 %d 个文件\n", len(deleted))
+	return nil
+}
+
+// confirm 在终端询问是否继续
+func confirm(prompt string) bool {
+	fmt.Print(prompt)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -2,10 +2,15 @@ package main
 
 import (
 	"log"
+	"os"
 	"time"
 
 	"moments-go/config"
 	"moments-go/handlers"
+	"moments-go/render"
+	"moments-go/store"
+	"moments-go/storage"
+	"moments-go/telegram"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
@@ -16,8 +21,33 @@ func main() {
 		log.Fatalf("加载配置失败: %v", err)
 	}
 
-	// 创建机器人实例
-	bot, err := tgbotapi.NewBotAPI(config.Cfg.TelegramBotToken)
+	// 命令行子命令
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "gc":
+			if err := storage.Init(); err != nil {
+				log.Fatalf("初始化媒体存储失败: %v", err)
+			}
+			if err := runGC(os.Args[2:]); err != nil {
+				log.Fatalf("清理失败: %v", err)
+			}
+			return
+		case "migrate":
+			if err := store.Open(config.Cfg.DataDir); err != nil {
+				log.Fatalf("加载动态索引失败: %v", err)
+			}
+			if err := storage.Init(); err != nil {
+				log.Fatalf("初始化媒体存储失败: %v", err)
+			}
+			if err := runMigrate(os.Args[2:]); err != nil {
+				log.Fatalf("改写地址失败: %v", err)
+			}
+			return
+		}
+	}
+
+	// 创建机器人实例，可以使用自建的 Bot API 服务器
+	bot, err := telegram.NewBotAPI()
 	if err != nil {
 		log.Fatalf("创建机器人失败: %v", err)
 	}
@@ -25,6 +55,42 @@ func main() {
 	bot.Debug = false
 	log.Printf("机器人已启动: %s", bot.Self.UserName)
 
+	// 加载本地动态索引并启动增量同步
+	if err := store.Open(config.Cfg.DataDir); err != nil {
+		log.Fatalf("加载动态索引失败: %v", err)
+	}
+	store.StartSync(time.Duration(config.IndexSyncInterval) * time.Second)
+
+	// 加载标题和正文模板
+	if err := render.Load(config.Cfg.TemplateDir, config.Cfg.Timezone); err != nil {
+		log.Fatalf("加载模板失败: %v", err)
+	}
+
+	// 加载水印
+	if err := handlers.LoadWatermark(); err != nil {
+		log.Fatalf("加载水印失败: %v", err)
+	}
+
+	// 创建下载文件的暂存目录
+	if err := telegram.InitSpool(); err != nil {
+		log.Fatalf("初始化暂存目录失败: %v", err)
+	}
+
+	// 初始化媒体存储，使用本地存储时启动 HTTP 服务
+	if err := storage.Init(); err != nil {
+		log.Fatalf("初始化媒体存储失败: %v", err)
+	}
+	if err := storage.ClaimIndex(); err != nil {
+		log.Fatalf("初始化媒体存储失败: %v", err)
+	}
+	storage.StartServer()
+
+	// 启动回收站定时清理
+	handlers.StartTrashPurger(bot)
+
+	// 启动文件仓库大小监控
+	handlers.StartQuotaMonitor(bot)
+
 	// 设置更新配置
 	updateConfig := tgbotapi.NewUpdate(0)
 	updateConfig.Timeout = 60
@@ -64,6 +130,20 @@ func main() {
 				err = handlers.HandlePhotoMessage(bot, update)
 			case update.Message.Video != nil:
 				err = handlers.HandleVideoMessage(bot, update)
+			case update.Message.Animation != nil:
+				// GIF 动画消息同时带有 Document 字段，需要优先处理
+				err = handlers.HandleAnimationMessage(bot, update)
+			case update.Message.Sticker != nil:
+				err = handlers.HandleStickerMessage(bot, update)
+			case update.Message.Venue != nil, update.Message.Location != nil:
+				// 地点消息同时带有 Location 字段
+				err = handlers.HandleLocationMessage(bot, update)
+			case update.Message.Document != nil:
+				err = handlers.HandleDocumentMessage(bot, update)
+			case update.Message.Voice != nil:
+				err = handlers.HandleVoiceMessage(bot, update)
+			case update.Message.Audio != nil:
+				err = handlers.HandleAudioMessage(bot, update)
 			case update.Message.Text != "":
 				err = handlers.HandleTextMessage(bot, update)
 			}
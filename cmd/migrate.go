@@ -0,0 +1,34 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"moments-go/storage"
+)
+
+// runMigrate 命令行将动态中文件仓库的地址改写为 MEDIA_URL_TEMPLATE 生成的地址：moments-go migrate [-y]
+func runMigrate(args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	yes := flags.Bool("y", false, "不询问，直接改写")
+	flags.Parse(args)
+
+	fmt.Println("正在查找需要改写地址的动态...")
+	migrations, err := storage.PlanURLMigration()
+	if err != nil {
+		return err
+	}
+	fmt.Print(storage.FormatURLMigration(migrations))
+	if len(migrations) == 0 {
+		return nil
+	}
+
+	if !*yes && !confirm(fmt.Sprintf("确认改写以上 %d 条动态？[y/N] ", len(migrations))) {
+		fmt.Println("已取消")
+		return nil
+	}
+
+	updated, err := storage.ApplyURLMigration(migrations)
+	fmt.Printf("已更新 %d 条动态\n", updated)
+	return err
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -15,8 +17,25 @@ import (
 const (
 	WaitTime    = 5 * 60 // 5分钟等待时间（秒）
 	MaxFileSize = 50 * 1024 * 1024 // 50MB
+	MaxReleaseAssetSize = 2 * 1024 * 1024 * 1024 // Release 附件大小上限（2GB）
+	MaxBotAPIDownloadSize = 20 * 1024 * 1024 // Bot API 允许机器人下载的文件大小上限（20MB）
+	MaxLocalBotAPIFileSize = 2 * 1024 * 1024 * 1024 // 本地模式的 Bot API 服务器文件大小上限（2GB）
+	DefaultTelegramAPIURL = "https://api.telegram.org" // 官方 Bot API 地址
+	DefaultReleaseAssetThreshold = 20 // 默认超过 20MB 的视频上传为 Release 附件
 	LabelCacheTime = 30 * 60 // 标签缓存时间（30分钟）
-	PublishedMomentCacheTime = 24 * 60 * 60 // 已发布动态缓存时间（24小时）
+	IndexSyncInterval = 10 * 60 // 本地动态索引同步间隔（10分钟）
+	TrashPurgeInterval = 6 * 60 * 60 // 回收站清理检查间隔（6小时）
+	DefaultUndoWindow = 5 * 60 // 默认撤回时间窗口（5分钟）
+	DefaultImageMaxEdge = 2048 // 默认图片最长边（像素）
+	DefaultImageQuality = 85 // 默认 JPEG 编码质量
+	DefaultImageThumbnailEdge = 320 // 默认缩略图最长边（像素）
+	DefaultWatermarkOpacity = 0.5 // 默认水印不透明度
+	DefaultWatermarkScale = 0.2 // 默认水印宽度占图片宽度的比例
+	QuotaCheckInterval = 60 * 60 // 文件仓库大小检查间隔（1小时）
+	DefaultUploadConcurrency = 3 // 默认同时上传的文件数
+	MaxUploadAttempts = 3 // 单个文件上传失败时的最大尝试次数
+	DefaultMediaPathTemplate = "moments/{{hash}}/{{name}}.{{ext}}" // 默认文件路径模板，保留原始文件名
+	JSDelivrURLTemplate = "https://cdn.jsdelivr.net/gh/{{owner}}/{{repo}}@{{branch}}/{{path}}" // jsDelivr 地址模板
 )
 
 var (
@@ -29,15 +48,51 @@ var (
 	LabelsCacheTime time.Time
 	LabelsMutex     sync.RWMutex
 	
-	// 已发布动态缓存
-	PublishedMoments = make(map[int]*types.PublishedMoment) // IssueNumber -> PublishedMoment
-	PublishedMutex   sync.RWMutex
-	
 	// 编辑状态管理
 	EditStates = make(map[int64]*EditState) // ChatID -> EditState
 	EditMutex  sync.RWMutex
+	
+	// 动态浏览状态管理
+	BrowseStates = make(map[int64]*BrowseState) // ChatID -> BrowseState
+	BrowseMutex  sync.RWMutex
+	
+	// 撤回记录管理
+	UndoRecords = make(map[int]*UndoRecord) // IssueNumber -> UndoRecord
+	UndoMutex   sync.Mutex
+	
+	// 切换前使用过的文件仓库，旧动态中的地址仍然指向这些仓库
+	PreviousFileRepos []string
+	FileRepoMutex     sync.RWMutex
 )
 
+// UndoRecord 刚发布的动态的撤回信息
+type UndoRecord struct {
+	ChatID      int64                `json:"chat_id"`
+	IssueNumber int                  `json:"issue_number"`
+	NodeID      string               `json:"node_id"`
+	Draft       types.PendingMedia   `json:"draft"` // 撤回后恢复的草稿
+	Files       []types.UploadedFile `json:"files"` // 本次上传到文件仓库的文件和 Release 附件
+	ExpiresAt   int64                `json:"expires_at"`
+}
+
+// Expired 撤回时间窗口是否已结束
+func (r *UndoRecord) Expired() bool {
+	return time.Now().Unix() > r.ExpiresAt
+}
+
+// BrowseState 动态浏览器的分页和筛选状态
+type BrowseState struct {
+	Label string `json:"label"`
+	Month string `json:"month"` // 格式 2006-01
+	Page  int    `json:"page"`
+
+	// 搜索模式（/search）下的条件
+	Search   bool   `json:"search"`
+	Keywords string `json:"keywords"`
+	After    string `json:"after"`  // 格式 2006-01-02
+	Before   string `json:"before"` // 格式 2006-01-02
+}
+
 // EditState 编辑状态
 type EditState struct {
 	IssueNumber int      `json:"issue_number"`
@@ -68,6 +123,25 @@ func LoadConfig() error {
 	}
 	Cfg.TelegramUserID = userID
 
+	Cfg.TelegramAPIURL = strings.TrimRight(os.Getenv("TELEGRAM_API_URL"), "/")
+	if Cfg.TelegramAPIURL == "" {
+		Cfg.TelegramAPIURL = DefaultTelegramAPIURL // 默认值
+	}
+	if !strings.HasPrefix(Cfg.TelegramAPIURL, "http://") && !strings.HasPrefix(Cfg.TelegramAPIURL, "https://") {
+		return fmt.Errorf("无效的 TELEGRAM_API_URL: %s", Cfg.TelegramAPIURL)
+	}
+
+	if localStr := os.Getenv("TELEGRAM_LOCAL_MODE"); localStr != "" {
+		localMode, err := strconv.ParseBool(localStr)
+		if err != nil {
+			return fmt.Errorf("无效的 TELEGRAM_LOCAL_MODE: %s", localStr)
+		}
+		Cfg.TelegramLocalMode = localMode
+	}
+	if Cfg.TelegramLocalMode && Cfg.TelegramAPIURL == DefaultTelegramAPIURL {
+		return fmt.Errorf("TELEGRAM_LOCAL_MODE 需要同时设置 TELEGRAM_API_URL 为自建的 Bot API 服务器")
+	}
+
 	Cfg.GitHubSecret = os.Getenv("GITHUB_SECRET")
 	if Cfg.GitHubSecret == "" {
 		return fmt.Errorf("GITHUB_SECRET 未设置")
@@ -94,6 +168,223 @@ func LoadConfig() error {
 		Cfg.GitHubUserAgent = "moments-bot/1.0" // 默认值
 	}
 
+	Cfg.GitHubUploadAPI = os.Getenv("GITHUB_UPLOAD_API")
+	switch Cfg.GitHubUploadAPI {
+	case "":
+		Cfg.GitHubUploadAPI = "git" // 默认值
+	case "git", "contents":
+	default:
+		return fmt.Errorf("无效的 GITHUB_UPLOAD_API: %s", Cfg.GitHubUploadAPI)
+	}
+
+	Cfg.ReleaseAssetThreshold = DefaultReleaseAssetThreshold * 1024 * 1024
+	if thresholdStr := os.Getenv("RELEASE_ASSET_THRESHOLD_MB"); thresholdStr != "" {
+		threshold, err := strconv.ParseInt(thresholdStr, 10, 64)
+		if err != nil || threshold < 0 {
+			return fmt.Errorf("无效的 RELEASE_ASSET_THRESHOLD_MB: %s", thresholdStr)
+		}
+		Cfg.ReleaseAssetThreshold = threshold * 1024 * 1024
+	}
+
+	Cfg.FileRepoWarnMB = []int64{800, 1000}
+	if warnStr := os.Getenv("FILE_REPO_WARN_MB"); warnStr != "" {
+		Cfg.FileRepoWarnMB = nil
+		for _, item := range strings.Split(warnStr, ",") {
+			threshold, err := strconv.ParseInt(strings.TrimSpace(item), 10, 64)
+			if err != nil || threshold < 0 {
+				return fmt.Errorf("无效的 FILE_REPO_WARN_MB: %s", warnStr)
+			}
+			if threshold > 0 {
+				Cfg.FileRepoWarnMB = append(Cfg.FileRepoWarnMB, threshold)
+			}
+		}
+	}
+
+	if rolloverStr := os.Getenv("FILE_REPO_ROLLOVER_MB"); rolloverStr != "" {
+		rollover, err := strconv.ParseInt(rolloverStr, 10, 64)
+		if err != nil || rollover < 0 {
+			return fmt.Errorf("无效的 FILE_REPO_ROLLOVER_MB: %s", rolloverStr)
+		}
+		Cfg.FileRepoRolloverMB = rollover
+	}
+
+	Cfg.MediaPathTemplate = strings.Trim(os.Getenv("MEDIA_PATH_TEMPLATE"), "/")
+	if Cfg.MediaPathTemplate == "" {
+		Cfg.MediaPathTemplate = DefaultMediaPathTemplate // 默认值
+	}
+	// 路径必须包含哈希，保证不同内容不会写到同一路径
+	if !strings.Contains(Cfg.MediaPathTemplate, "{{hash}}") {
+		return fmt.Errorf("无效的 MEDIA_PATH_TEMPLATE: %s（必须包含 {{hash}}）", Cfg.MediaPathTemplate)
+	}
+
+	Cfg.MediaURLTemplate = os.Getenv("MEDIA_URL_TEMPLATE")
+	if Cfg.MediaURLTemplate == "jsdelivr" {
+		Cfg.MediaURLTemplate = JSDelivrURLTemplate
+	}
+	if Cfg.MediaURLTemplate != "" && !strings.Contains(Cfg.MediaURLTemplate, "{{path}}") {
+		return fmt.Errorf("无效的 MEDIA_URL_TEMPLATE: %s（必须包含 {{path}}）", Cfg.MediaURLTemplate)
+	}
+
+	Cfg.DataDir = os.Getenv("DATA_DIR")
+	if Cfg.DataDir == "" {
+		Cfg.DataDir = "data" // 默认值
+	}
+
+	Cfg.SpoolDir = os.Getenv("SPOOL_DIR")
+	if Cfg.SpoolDir == "" {
+		Cfg.SpoolDir = filepath.Join(Cfg.DataDir, "tmp") // 默认值
+	}
+
+	// 媒体存储，MEDIA_STORE 为默认存储，MEDIA_STORE_<类型> 覆盖对应类型
+	Cfg.MediaStores = make(map[string]string)
+	for _, kind := range []string{"", "image", "video", "audio", "file"} {
+		key := "MEDIA_STORE"
+		if kind != "" {
+			key += "_" + strings.ToUpper(kind)
+		}
+		store := os.Getenv(key)
+		switch store {
+		case "":
+			continue
+		case "github", "s3", "local":
+			Cfg.MediaStores[kind] = store
+		default:
+			return fmt.Errorf("无效的 %s: %s", key, store)
+		}
+	}
+
+	Cfg.S3Endpoint = strings.TrimRight(os.Getenv("S3_ENDPOINT"), "/")
+	Cfg.S3Region = os.Getenv("S3_REGION")
+	if Cfg.S3Region == "" {
+		Cfg.S3Region = "us-east-1" // 默认值
+	}
+	Cfg.S3Bucket = os.Getenv("S3_BUCKET")
+	Cfg.S3AccessKey = os.Getenv("S3_ACCESS_KEY")
+	Cfg.S3SecretKey = os.Getenv("S3_SECRET_KEY")
+	Cfg.S3PublicURL = strings.TrimRight(os.Getenv("S3_PUBLIC_URL"), "/")
+
+	Cfg.LocalStorageDir = os.Getenv("LOCAL_STORAGE_DIR")
+	if Cfg.LocalStorageDir == "" {
+		Cfg.LocalStorageDir = filepath.Join(Cfg.DataDir, "media") // 默认值
+	}
+	Cfg.LocalPublicURL = strings.TrimRight(os.Getenv("LOCAL_PUBLIC_URL"), "/")
+	Cfg.HTTPListen = os.Getenv("HTTP_LISTEN")
+	if Cfg.HTTPListen == "" {
+		Cfg.HTTPListen = ":8080" // 默认值
+	}
+
+	Cfg.TemplateDir = os.Getenv("TEMPLATE_DIR")
+	if Cfg.TemplateDir == "" {
+		Cfg.TemplateDir = "templates" // 默认值
+	}
+	Cfg.Timezone = os.Getenv("TIMEZONE")
+
+	if metadataStr := os.Getenv("METADATA_BLOCK"); metadataStr != "" {
+		metadataBlock, err := strconv.ParseBool(metadataStr)
+		if err != nil {
+			return fmt.Errorf("无效的 METADATA_BLOCK: %s", metadataStr)
+		}
+		Cfg.MetadataBlock = metadataBlock
+	}
+
+	if processingStr := os.Getenv("IMAGE_PROCESSING"); processingStr != "" {
+		processing, err := strconv.ParseBool(processingStr)
+		if err != nil {
+			return fmt.Errorf("无效的 IMAGE_PROCESSING: %s", processingStr)
+		}
+		Cfg.ImageProcessing = processing
+	}
+
+	Cfg.ImageMaxEdge = DefaultImageMaxEdge
+	if edgeStr := os.Getenv("IMAGE_MAX_EDGE"); edgeStr != "" {
+		edge, err := strconv.Atoi(edgeStr)
+		if err != nil || edge < 0 {
+			return fmt.Errorf("无效的 IMAGE_MAX_EDGE: %s", edgeStr)
+		}
+		Cfg.ImageMaxEdge = edge
+	}
+
+	Cfg.ImageQuality = DefaultImageQuality
+	if qualityStr := os.Getenv("IMAGE_QUALITY"); qualityStr != "" {
+		quality, err := strconv.Atoi(qualityStr)
+		if err != nil || quality < 1 || quality > 100 {
+			return fmt.Errorf("无效的 IMAGE_QUALITY: %s", qualityStr)
+		}
+		Cfg.ImageQuality = quality
+	}
+
+	Cfg.ImageThumbnailEdge = DefaultImageThumbnailEdge
+	if thumbStr := os.Getenv("IMAGE_THUMBNAIL_EDGE"); thumbStr != "" {
+		thumb, err := strconv.Atoi(thumbStr)
+		if err != nil || thumb < 0 {
+			return fmt.Errorf("无效的 IMAGE_THUMBNAIL_EDGE: %s", thumbStr)
+		}
+		Cfg.ImageThumbnailEdge = thumb
+	}
+
+	Cfg.WatermarkText = os.Getenv("WATERMARK_TEXT")
+	Cfg.WatermarkLogo = os.Getenv("WATERMARK_LOGO")
+	Cfg.WatermarkFont = os.Getenv("WATERMARK_FONT")
+	Cfg.WatermarkPosition = os.Getenv("WATERMARK_POSITION")
+	if Cfg.WatermarkPosition == "" {
+		Cfg.WatermarkPosition = "bottom-right" // 默认值
+	}
+
+	Cfg.WatermarkOpacity = DefaultWatermarkOpacity
+	if opacityStr := os.Getenv("WATERMARK_OPACITY"); opacityStr != "" {
+		opacity, err := strconv.ParseFloat(opacityStr, 64)
+		if err != nil || opacity <= 0 || opacity > 1 {
+			return fmt.Errorf("无效的 WATERMARK_OPACITY: %s", opacityStr)
+		}
+		Cfg.WatermarkOpacity = opacity
+	}
+
+	Cfg.WatermarkScale = DefaultWatermarkScale
+	if scaleStr := os.Getenv("WATERMARK_SCALE"); scaleStr != "" {
+		scale, err := strconv.ParseFloat(scaleStr, 64)
+		if err != nil || scale <= 0 || scale > 1 {
+			return fmt.Errorf("无效的 WATERMARK_SCALE: %s", scaleStr)
+		}
+		Cfg.WatermarkScale = scale
+	}
+
+	for _, label := range strings.Split(os.Getenv("WATERMARK_LABELS"), ",") {
+		if label = strings.TrimSpace(label); label != "" {
+			Cfg.WatermarkLabels = append(Cfg.WatermarkLabels, label)
+		}
+	}
+
+	Cfg.CheckinLabel = os.Getenv("CHECKIN_LABEL")
+	if Cfg.CheckinLabel == "" {
+		Cfg.CheckinLabel = "打卡" // 默认值
+	}
+
+	Cfg.UndoWindow = DefaultUndoWindow
+	if undoStr := os.Getenv("UNDO_WINDOW"); undoStr != "" {
+		undoWindow, err := strconv.Atoi(undoStr)
+		if err != nil || undoWindow < 0 {
+			return fmt.Errorf("无效的 UNDO_WINDOW: %s", undoStr)
+		}
+		Cfg.UndoWindow = undoWindow
+	}
+
+	Cfg.UploadConcurrency = DefaultUploadConcurrency
+	if concurrencyStr := os.Getenv("UPLOAD_CONCURRENCY"); concurrencyStr != "" {
+		concurrency, err := strconv.Atoi(concurrencyStr)
+		if err != nil || concurrency < 1 {
+			return fmt.Errorf("无效的 UPLOAD_CONCURRENCY: %s", concurrencyStr)
+		}
+		Cfg.UploadConcurrency = concurrency
+	}
+
+	if retentionStr := os.Getenv("TRASH_RETENTION_DAYS"); retentionStr != "" {
+		retention, err := strconv.Atoi(retentionStr)
+		if err != nil || retention < 0 {
+			return fmt.Errorf("无效的 TRASH_RETENTION_DAYS: %s", retentionStr)
+		}
+		Cfg.TrashRetentionDays = retention
+	}
+
 	return nil
 }
 
@@ -101,6 +392,28 @@ func IsAuthorizedUser(chatID int64) bool {
 	return chatID == Cfg.TelegramUserID
 }
 
+// GetFileRepo 获取当前的文件仓库，自动切换后会在运行时更新
+func GetFileRepo() string {
+	FileRepoMutex.RLock()
+	defer FileRepoMutex.RUnlock()
+	return Cfg.GitHubFileRepo
+}
+
+// GetFileRepos 获取所有使用过的文件仓库，当前仓库在前
+func GetFileRepos() []string {
+	FileRepoMutex.RLock()
+	defer FileRepoMutex.RUnlock()
+	return append([]string{Cfg.GitHubFileRepo}, PreviousFileRepos...)
+}
+
+// SetFileRepos 设置当前的文件仓库和之前使用过的文件仓库
+func SetFileRepos(current string, previous []string) {
+	FileRepoMutex.Lock()
+	defer FileRepoMutex.Unlock()
+	Cfg.GitHubFileRepo = current
+	PreviousFileRepos = previous
+}
+
 // GetLabels 获取标签列表（带缓存）
 func GetLabels() []string {
 	LabelsMutex.RLock()
@@ -122,21 +435,6 @@ func SetLabels(labels []string) {
 	LabelsCacheTime = time.Now()
 }
 
-// AddPublishedMoment 添加已发布的动态到缓存
-func AddPublishedMoment(moment *types.PublishedMoment) {
-	PublishedMutex.Lock()
-	defer PublishedMutex.Unlock()
-	PublishedMoments[moment.IssueNumber] = moment
-}
-
-// GetPublishedMoment 获取已发布的动态
-func GetPublishedMoment(issueNumber int) (*types.PublishedMoment, bool) {
-	PublishedMutex.RLock()
-	defer PublishedMutex.RUnlock()
-	moment, exists := PublishedMoments[issueNumber]
-	return moment, exists
-}
-
 // SetEditState 设置编辑状态
 func SetEditState(chatID int64, issueNumber int, originalContent string, originalLabels []string) {
 	EditMutex.Lock()
@@ -171,4 +469,51 @@ func IsInEditMode(chatID int64) bool {
 	defer EditMutex.RUnlock()
 	_, exists := EditStates[chatID]
 	return exists
-} 
+}
+
+// GetBrowseState 获取浏览状态（返回副本，不存在时返回第一页）
+func GetBrowseState(chatID int64) BrowseState {
+	BrowseMutex.RLock()
+	defer BrowseMutex.RUnlock()
+	state, exists := BrowseStates[chatID]
+	if !exists {
+		return BrowseState{Page: 1}
+	}
+	return *state
+}
+
+// SetBrowseState 设置浏览状态
+func SetBrowseState(chatID int64, state BrowseState) {
+	BrowseMutex.Lock()
+	defer BrowseMutex.Unlock()
+	if state.Page < 1 {
+		state.Page = 1
+	}
+	BrowseStates[chatID] = &state
+}
+
+// SetUndoRecord 保存撤回记录
+func SetUndoRecord(record *UndoRecord) {
+	UndoMutex.Lock()
+	defer UndoMutex.Unlock()
+	UndoRecords[record.IssueNumber] = record
+}
+
+// TakeUndoRecord 取出并移除撤回记录，保证同一条动态只会被撤回一次
+func TakeUndoRecord(issueNumber int) (*UndoRecord, bool) {
+	UndoMutex.Lock()
+	defer UndoMutex.Unlock()
+	record, exists := UndoRecords[issueNumber]
+	if exists {
+		delete(UndoRecords, issueNumber)
+	}
+	return record, exists
+}
+
+// MaxDownloadSize 机器人能从 Telegram 下载的文件大小上限：本地模式的 Bot API 服务器为 2GB，否则为 20MB
+func MaxDownloadSize() int64 {
+	if Cfg.TelegramLocalMode {
+		return MaxLocalBotAPIFileSize
+	}
+	return MaxBotAPIDownloadSize
+}
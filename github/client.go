@@ -25,25 +25,32 @@ func NewGitHubClient() *GitHubClient {
 
 // makeRequest 发送 HTTP 请求到 GitHub API
 func (c *GitHubClient) makeRequest(method, url string, body interface{}) (*http.Response, error) {
-	var reqBody io.Reader
-	if body != nil {
-		jsonData, err := json.Marshal(body)
-		if err != nil {
-			return nil, fmt.Errorf("序列化数据失败: %v", err)
-		}
-		reqBody = bytes.NewBuffer(jsonData)
+	if body == nil {
+		return c.makeStreamRequest(method, url, "", nil, 0)
 	}
 
-	req, err := http.NewRequest(method, url, reqBody)
+	jsonData, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("序列化数据失败: %v", err)
+	}
+	return c.makeStreamRequest(method, url, "application/json", bytes.NewReader(jsonData), int64(len(jsonData)))
+}
+
+// makeStreamRequest 以流的形式发送请求体，避免大文件在内存中整体编码；length 为 -1 时使用分块传输
+func (c *GitHubClient) makeStreamRequest(method, url, contentType string, body io.Reader, length int64) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, body)
 	if err != nil {
 		return nil, fmt.Errorf("创建请求失败: %v", err)
 	}
+	if body != nil {
+		req.ContentLength = length
+	}
 
 	req.Header.Set("Authorization", "Bearer "+c.token)
 	req.Header.Set("Accept", "application/vnd.github.v3+json")
 	req.Header.Set("User-Agent", config.Cfg.GitHubUserAgent)
-	if body != nil {
-		req.Header.Set("Content-Type", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
 	}
 
 	resp, err := c.client.Do(req)
@@ -70,4 +77,39 @@ func (c *GitHubClient) handleResponse(resp *http.Response, target interface{}) e
 	}
 
 	return nil
-} 
+}
+
+// graphQL 发送 GraphQL 请求，部分操作（如删除 Issue）只能通过 GraphQL 完成
+func (c *GitHubClient) graphQL(query string, variables map[string]interface{}, target interface{}) error {
+	payload := map[string]interface{}{
+		"query":     query,
+		"variables": variables,
+	}
+
+	resp, err := c.makeRequest("POST", "https://api.github.com/graphql", payload)
+	if err != nil {
+		return err
+	}
+
+	var result struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := c.handleResponse(resp, &result); err != nil {
+		return err
+	}
+
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("GitHub GraphQL 请求失败: %s", result.Errors[0].Message)
+	}
+
+	if target != nil && len(result.Data) > 0 {
+		if err := json.Unmarshal(result.Data, target); err != nil {
+			return fmt.Errorf("解析响应失败: %v", err)
+		}
+	}
+
+	return nil
+}
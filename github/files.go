@@ -1,75 +1,290 @@
 package github
 
 import (
-	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"html"
 	"moments-go/config"
 	"moments-go/types"
-	"strconv"
-	"time"
-	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 )
 
-// UploadFileToGitHub 上传文件到 GitHub
-func UploadFileToGitHub(file *types.MediaFile, timestamp string) (string, error) {
+// UploadFileToGitHub 上传文件到 GitHub，边读取边进行 base64 编码，不在内存中保存完整的请求体
+func UploadFileToGitHub(file *types.MediaFile, timestamp string) (*types.UploadedFile, error) {
 	client := NewGitHubClient()
-	base64Content := base64.StdEncoding.EncodeToString(file.Content)
-	
-	uploadData := map[string]interface{}{
-		"message": fmt.Sprintf("Add media file: %s", file.Name),
-		"content": base64Content,
+	content, size, err := file.Reader()
+	if err != nil {
+		return nil, fmt.Errorf("读取文件 %s 失败: %v", file.Name, err)
+	}
+	defer content.Close()
+
+	message, err := json.Marshal(fmt.Sprintf("Add media file: %s", file.Name))
+	if err != nil {
+		return nil, err
 	}
+	body, length := base64JSONBody(fmt.Sprintf(`{"message":%s,"content":"`, message), content, size, `"}`)
 
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/contents/moments/%s_%s", 
-		config.Cfg.GitHubUsername, config.Cfg.GitHubFileRepo, timestamp, file.Name)
+	path := MediaPath(file, timestamp)
+	url := contentsURL(config.GetFileRepo(), path)
 	
-	resp, err := client.makeRequest("PUT", url, uploadData)
+	resp, err := client.makeStreamRequest("PUT", url, "application/json", body, length)
 	if err != nil {
-		return "", err
+		body.Close()
+		return nil, err
 	}
 
 	var uploadResult types.GitHubUploadResponse
 	if err := client.handleResponse(resp, &uploadResult); err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if uploadResult.Content == nil || uploadResult.Content.DownloadURL == "" {
-		return "", fmt.Errorf("文件 %s 上传失败", file.Name)
+		return nil, fmt.Errorf("文件 %s 上传失败", file.Name)
 	}
 
-	return uploadResult.Content.DownloadURL, nil
+	if config.Cfg.MediaURLTemplate != "" {
+		branch, err := getFileRepoBranch(client)
+		if err != nil {
+			return nil, err
+		}
+		return &types.UploadedFile{Path: path, URL: RepoFileURL(config.GetFileRepo(), branch, path), Repo: config.GetFileRepo()}, nil
+	}
+	return &types.UploadedFile{Path: path, URL: uploadResult.Content.DownloadURL, Repo: config.GetFileRepo()}, nil
 }
 
-// UploadToGitHub 上传媒体文件到 GitHub 并发布动态
-func UploadToGitHub(bot *tgbotapi.BotAPI, content string, mediaFiles []*types.MediaFile) (*types.GitHubIssueResponse, error) {
-	return UploadToGitHubWithLabels(bot, content, mediaFiles, []string{"动态"})
+// MatchUploadedMedia 根据上传顺序将地址对应到媒体文件和封面缩略图
+func MatchUploadedMedia(mediaFiles, files []*types.MediaFile, uploaded []types.UploadedFile) []UploadedMedia {
+	urls := make(map[*types.MediaFile]string)
+	for i, file := range files {
+		urls[file] = uploaded[i].URL
+	}
+
+	var media []UploadedMedia
+	for _, file := range mediaFiles {
+		item := UploadedMedia{File: file, URL: urls[file]}
+		if file.Thumbnail != nil {
+			item.ThumbnailURL = urls[file.Thumbnail]
+		}
+		media = append(media, item)
+	}
+	return media
 }
 
-// UploadToGitHubWithLabels 上传媒体文件到 GitHub 并发布带标签的动态
-func UploadToGitHubWithLabels(bot *tgbotapi.BotAPI, content string, mediaFiles []*types.MediaFile, labels []string) (*types.GitHubIssueResponse, error) {
-	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
-	var mediaUrls []string
+// contentsMutex contents 接口每个文件一次提交，同时提交会因分支被抢先更新而失败
+var contentsMutex sync.Mutex
+
+// StageFile 上传单个文件，可以并发调用：大视频上传为 Release 附件，contents 接口直接提交；
+// Git Data API 只创建 blob（记录在 Blob 中），之后由 CommitFiles 在一次提交中添加
+func StageFile(file *types.MediaFile, timestamp string) (*types.UploadedFile, error) {
+	if file.ReleaseAsset {
+		return uploadReleaseFile(file, timestamp)
+	}
+	if config.Cfg.GitHubUploadAPI == "contents" {
+		contentsMutex.Lock()
+		defer contentsMutex.Unlock()
+		return UploadFileToGitHub(file, timestamp)
+	}
+
+	sha, err := createFileBlob(NewGitHubClient(), file)
+	if err != nil {
+		return nil, fmt.Errorf("上传文件 %s 失败: %v", file.Name, err)
+	}
+	return &types.UploadedFile{Path: MediaPath(file, timestamp), Blob: sha}, nil
+}
 
-	if len(mediaFiles) > 0 {
-		if err := SendMessage(bot, config.Cfg.TelegramUserID, "📤 正在上传媒体文件..."); err != nil {
+// uploadReleaseFile 以流的形式读取大文件并上传为 Release 附件
+func uploadReleaseFile(file *types.MediaFile, timestamp string) (*types.UploadedFile, error) {
+	content, size, err := file.Reader()
+	if err != nil {
+		return nil, fmt.Errorf("读取文件 %s 失败: %v", file.Name, err)
+	}
+	defer content.Close()
+
+	if size <= 0 {
+		size = -1
+	}
+	return UploadReleaseAsset(MediaFileName(file, timestamp), file.Type, content, size)
+}
+
+// FindRepoFile 查找文件仓库中已存在的文件，不存在时返回 nil
+func FindRepoFile(path string) (*types.UploadedFile, error) {
+	client := NewGitHubClient()
+	resp, err := client.makeRequest("GET", contentsURL(config.GetFileRepo(), path), nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, nil
+	}
+
+	var content struct {
+		DownloadURL string `json:"download_url"`
+	}
+	if err := client.handleResponse(resp, &content); err != nil {
+		return nil, err
+	}
+
+	// 与上传时返回的地址保持一致
+	if config.Cfg.GitHubUploadAPI != "contents" || config.Cfg.MediaURLTemplate != "" {
+		branch, err := getFileRepoBranch(client)
+		if err != nil {
 			return nil, err
 		}
+		return &types.UploadedFile{Path: path, URL: RepoFileURL(config.GetFileRepo(), branch, path), Repo: config.GetFileRepo()}, nil
+	}
+	return &types.UploadedFile{Path: path, URL: content.DownloadURL, Repo: config.GetFileRepo()}, nil
+}
 
-		for _, file := range mediaFiles {
-			downloadURL, err := UploadFileToGitHub(file, timestamp)
-			if err != nil {
-				return nil, fmt.Errorf("上传文件 %s 失败: %v", file.Name, err)
+// TGSStickerType Telegram 动画贴纸（gzip 压缩的 Lottie JSON）的 MIME 类型
+const TGSStickerType = "application/x-tgsticker"
+
+// RenderEmbed 生成媒体文件在动态内容中的引用：图片内嵌显示，视频和音频使用播放器，其他文件以下载链接显示
+func RenderEmbed(file *types.MediaFile, url, thumbnailURL string) string {
+	switch {
+	case strings.HasPrefix(file.Type, "video/"):
+		attrs := "controls preload=\"metadata\""
+		if file.Loop {
+			attrs = "autoplay loop muted playsinline"
+		}
+		attrs += sizeAttrs(file)
+		if thumbnailURL != "" {
+			attrs += fmt.Sprintf(` poster="%s"`, thumbnailURL)
+		}
+		return fmt.Sprintf(`<video %s src="%s"></video>`, attrs, url)
+	case file.Type == TGSStickerType:
+		// Lottie 动画贴纸无法直接显示，使用静态缩略图并链接到原文件
+		if thumbnailURL != "" {
+			return fmt.Sprintf("[![贴纸](%s)](%s)", thumbnailURL, url)
+		}
+		return fmt.Sprintf("[🧩 %s](%s)", file.Name, url)
+	case strings.HasPrefix(file.Type, "image/"):
+		alt := file.Alt
+		if alt == "" {
+			alt = "图片"
+		}
+		if file.BlurHash != "" || file.DominantColor != "" {
+			// 带占位信息时使用 HTML，便于前端预留空间
+			src := url
+			if thumbnailURL != "" {
+				src = thumbnailURL
+			}
+			img := fmt.Sprintf(`<img src="%s" alt="%s"%s%s>`, src, html.EscapeString(alt), sizeAttrs(file), placeholderAttrs(file))
+			if thumbnailURL != "" {
+				// 显示缩略图，点击打开原图
+				img = fmt.Sprintf(`<a href="%s">%s</a>`, url, img)
 			}
-			mediaUrls = append(mediaUrls, downloadURL)
+			return img
+		}
+		if thumbnailURL != "" {
+			// 显示缩略图，点击打开原图
+			return fmt.Sprintf("[![%s](%s)](%s)", alt, thumbnailURL, url)
+		}
+		return fmt.Sprintf("![%s](%s)", alt, url)
+	case strings.HasPrefix(file.Type, "audio/"):
+		embed := fmt.Sprintf(`<audio controls preload="metadata" src="%s"></audio>`, url)
+		if info := audioInfo(file); info != "" {
+			embed += "\n" + info
+		}
+		if thumbnailURL != "" {
+			embed += fmt.Sprintf("\n![封面](%s)", thumbnailURL)
 		}
+		return embed
+	default:
+		return fmt.Sprintf("[📎 %s](%s)", file.Name, url)
 	}
+}
 
-	fullContent := content
-	if len(mediaUrls) > 0 {
-		for _, url := range mediaUrls {
-			fullContent += fmt.Sprintf("\n![%s](%s)", url, url)
-		}
+// placeholderAttrs 生成占位信息的 HTML 属性，主色调和 BlurHash 各自在有值时写入
+func placeholderAttrs(file *types.MediaFile) string {
+	var attrs string
+	if file.DominantColor != "" {
+		attrs += fmt.Sprintf(` data-dominant-color="%s"`, html.EscapeString(file.DominantColor))
+	}
+	if file.BlurHash != "" {
+		attrs += fmt.Sprintf(` data-blurhash="%s"`, html.EscapeString(file.BlurHash))
+	}
+	return attrs
+}
+
+// sizeAttrs 生成 HTML 的宽高属性，缺少尺寸时返回空字符串
+func sizeAttrs(file *types.MediaFile) string {
+	if file.Width <= 0 || file.Height <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(` width="%d" height="%d"`, file.Width, file.Height)
+}
+
+// audioInfo 生成音频的描述：标题、表演者和时长
+func audioInfo(file *types.MediaFile) string {
+	var parts []string
+	switch {
+	case file.Title != "" && file.Performer != "":
+		parts = append(parts, fmt.Sprintf("🎵 %s - %s", file.Title, file.Performer))
+	case file.Title != "":
+		parts = append(parts, "🎵 "+file.Title)
+	case file.Performer != "":
+		parts = append(parts, "🎵 "+file.Performer)
+	}
+	if file.Duration > 0 {
+		parts = append(parts, "⏱ "+FormatDuration(file.Duration))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// FormatDuration 将秒数格式化为 m:ss 或 h:mm:ss
+func FormatDuration(seconds int) string {
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds%3600/60, seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+// contentsURL 生成文件仓库 contents 接口地址，路径逐段转义
+func contentsURL(repo, path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return fmt.Sprintf("https://api.github.com/repos/%s/%s/contents/%s",
+		config.Cfg.GitHubUsername, repo, strings.Join(segments, "/"))
+}
+
+// DeleteFileFromGitHub 从文件仓库删除指定路径的文件
+func DeleteFileFromGitHub(repo, path string) error {
+	client := NewGitHubClient()
+	apiURL := contentsURL(repo, path)
+
+	// 删除文件需要提供当前的 sha
+	resp, err := client.makeRequest("GET", apiURL, nil)
+	if err != nil {
+		return err
 	}
 
-	return CreateGitHubIssueWithLabels(fullContent, labels)
-} 
+	var file struct {
+		SHA string `json:"sha"`
+	}
+	if err := client.handleResponse(resp, &file); err != nil {
+		return err
+	}
+
+	deleteData := map[string]interface{}{
+		"message": fmt.Sprintf("Delete media file: %s", path),
+		"sha":     file.SHA,
+	}
+
+	resp, err = client.makeRequest("DELETE", apiURL, deleteData)
+	if err != nil {
+		return err
+	}
+
+	return client.handleResponse(resp, nil)
+}
+
+// ExtractMediaPaths 从动态内容中提取当前文件仓库中的媒体文件路径，支持 raw.githubusercontent.com 地址和 MEDIA_URL_TEMPLATE 生成的地址
+func ExtractMediaPaths(body string) []string {
+	return extractRepoPaths(body, config.GetFileRepo())
+}
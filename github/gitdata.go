@@ -0,0 +1,319 @@
+package github
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"moments-go/config"
+	"moments-go/types"
+)
+
+// maxRefRetries 更新分支时遇到并发提交（非快进）的最大重试次数
+const maxRefRetries = 5
+
+// gitTreeEntry Git 树中的条目，SHA 为 nil 时表示删除该文件
+type gitTreeEntry struct {
+	Path string  `json:"path"`
+	Mode string  `json:"mode"`
+	Type string  `json:"type"`
+	SHA  *string `json:"sha"`
+}
+
+// RepoFile 文件仓库中的文件
+type RepoFile struct {
+	Path string
+	Size int64
+}
+
+// CommitFiles 在一次提交中添加 StageFile 创建了 blob 的文件，返回带地址的文件，顺序与传入顺序一致；
+// 已经上传完成的文件（Release 附件、contents 接口）原样返回
+func CommitFiles(files []types.UploadedFile) ([]types.UploadedFile, error) {
+	var entries []gitTreeEntry
+	var names []string
+	for _, file := range files {
+		if file.Blob == "" {
+			continue
+		}
+		sha := file.Blob
+		entries = append(entries, gitTreeEntry{Path: file.Path, Mode: "100644", Type: "blob", SHA: &sha})
+		names = append(names, path.Base(file.Path))
+	}
+	if len(entries) == 0 {
+		return files, nil
+	}
+
+	branch, err := commitTree(NewGitHubClient(), entries, fmt.Sprintf("Add media files: %s", strings.Join(names, ", ")))
+	if err != nil {
+		return nil, err
+	}
+
+	repo := config.GetFileRepo()
+	committed := make([]types.UploadedFile, 0, len(files))
+	for _, file := range files {
+		if file.Blob != "" {
+			file = types.UploadedFile{Path: file.Path, URL: RepoFileURL(repo, branch, file.Path), Repo: repo}
+		}
+		committed = append(committed, file)
+	}
+	return committed, nil
+}
+
+// DeleteFilesFromGitHub 在一次提交中从文件仓库删除多个文件
+func DeleteFilesFromGitHub(paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+
+	var entries []gitTreeEntry
+	for _, path := range paths {
+		entries = append(entries, gitTreeEntry{Path: path, Mode: "100644", Type: "blob"})
+	}
+
+	_, err := commitTree(NewGitHubClient(), entries, fmt.Sprintf("Remove %d unreferenced media files", len(paths)))
+	return err
+}
+
+// ListRepoFiles 列出文件仓库默认分支中的所有文件
+func ListRepoFiles() ([]RepoFile, error) {
+	client := NewGitHubClient()
+
+	branch, err := getFileRepoBranch(client)
+	if err != nil {
+		return nil, err
+	}
+	_, tree, err := getBranchHead(client, branch)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.makeRequest("GET", gitDataURL("trees/"+tree+"?recursive=1"), nil)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Tree []struct {
+			Path string `json:"path"`
+			Type string `json:"type"`
+			Size int64  `json:"size"`
+		} `json:"tree"`
+		Truncated bool `json:"truncated"`
+	}
+	if err := client.handleResponse(resp, &result); err != nil {
+		return nil, fmt.Errorf("获取文件列表失败: %v", err)
+	}
+	if result.Truncated {
+		return nil, fmt.Errorf("文件仓库中的文件过多，GitHub 返回的文件列表不完整")
+	}
+
+	var files []RepoFile
+	for _, entry := range result.Tree {
+		if entry.Type == "blob" {
+			files = append(files, RepoFile{Path: entry.Path, Size: entry.Size})
+		}
+	}
+	return files, nil
+}
+
+// commitTree 基于分支最新提交修改树中的条目并提交，分支被其他提交抢先更新时基于新的提交重试。返回提交所在的分支
+func commitTree(client *GitHubClient, entries []gitTreeEntry, message string) (string, error) {
+	branch, err := getFileRepoBranch(client)
+	if err != nil {
+		return "", err
+	}
+
+	for attempt := 1; ; attempt++ {
+		parent, baseTree, err := getBranchHead(client, branch)
+		if err != nil {
+			return "", err
+		}
+
+		tree, err := createTree(client, baseTree, entries)
+		if err != nil {
+			return "", err
+		}
+
+		commit, err := createCommit(client, message, tree, parent)
+		if err != nil {
+			return "", err
+		}
+
+		updated, err := updateBranch(client, branch, commit)
+		if err != nil {
+			return "", err
+		}
+		if updated {
+			return branch, nil
+		}
+		if attempt >= maxRefRetries {
+			return "", fmt.Errorf("更新分支 %s 失败：多次遇到并发提交", branch)
+		}
+	}
+}
+
+// gitDataURL 生成当前文件仓库 Git Data 接口地址
+func gitDataURL(path string) string {
+	return fmt.Sprintf("https://api.github.com/repos/%s/%s/git/%s", config.Cfg.GitHubUsername, config.GetFileRepo(), path)
+}
+
+// rawFileURL 生成文件的 raw.githubusercontent.com 地址，与 contents 接口返回的 download_url 格式一致
+func rawFileURL(repo, branch, path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return fmt.Sprintf("https://raw.githubusercontent.com/%s/%s/%s/%s",
+		config.Cfg.GitHubUsername, repo, branch, strings.Join(segments, "/"))
+}
+
+// getFileRepoBranch 获取当前文件仓库的默认分支
+func getFileRepoBranch(client *GitHubClient) (string, error) {
+	return getRepoBranch(client, config.GetFileRepo())
+}
+
+// getBranchHead 获取分支最新提交及其树
+func getBranchHead(client *GitHubClient, branch string) (string, string, error) {
+	resp, err := client.makeRequest("GET", gitDataURL("ref/heads/"+url.PathEscape(branch)), nil)
+	if err != nil {
+		return "", "", err
+	}
+	var ref struct {
+		Object struct {
+			SHA string `json:"sha"`
+		} `json:"object"`
+	}
+	if err := client.handleResponse(resp, &ref); err != nil {
+		return "", "", fmt.Errorf("获取分支 %s 失败: %v", branch, err)
+	}
+
+	resp, err = client.makeRequest("GET", gitDataURL("commits/"+ref.Object.SHA), nil)
+	if err != nil {
+		return "", "", err
+	}
+	var commit struct {
+		Tree struct {
+			SHA string `json:"sha"`
+		} `json:"tree"`
+	}
+	if err := client.handleResponse(resp, &commit); err != nil {
+		return "", "", fmt.Errorf("获取提交 %s 失败: %v", ref.Object.SHA, err)
+	}
+
+	return ref.Object.SHA, commit.Tree.SHA, nil
+}
+
+// createFileBlob 打开文件内容并创建 blob
+func createFileBlob(client *GitHubClient, file *types.MediaFile) (string, error) {
+	content, size, err := file.Reader()
+	if err != nil {
+		return "", err
+	}
+	defer content.Close()
+	return createBlob(client, content, size)
+}
+
+// createBlob 流式创建 blob：边读取边进行 base64 编码并写入请求体，不在内存中保存完整的 JSON
+func createBlob(client *GitHubClient, content io.Reader, size int64) (string, error) {
+	reader, length := base64JSONBody(`{"encoding":"base64","content":"`, content, size, `"}`)
+	resp, err := client.makeStreamRequest("POST", gitDataURL("blobs"), "application/json", reader, length)
+	if err != nil {
+		reader.Close()
+		return "", err
+	}
+	var blob struct {
+		SHA string `json:"sha"`
+	}
+	if err := client.handleResponse(resp, &blob); err != nil {
+		return "", err
+	}
+	return blob.SHA, nil
+}
+
+// base64JSONBody 生成以 base64 编码内容为字符串值的 JSON 请求体：prefix 和 suffix 为内容前后的 JSON 片段，
+// 边读取边编码，不在内存中保存完整的请求体。size 未知（小于 0）时长度返回 -1
+func base64JSONBody(prefix string, content io.Reader, size int64, suffix string) (*io.PipeReader, int64) {
+	reader, writer := io.Pipe()
+	go func() {
+		if _, err := io.WriteString(writer, prefix); err != nil {
+			writer.CloseWithError(err)
+			return
+		}
+		encoder := base64.NewEncoder(base64.StdEncoding, writer)
+		if _, err := io.Copy(encoder, content); err != nil {
+			writer.CloseWithError(err)
+			return
+		}
+		if err := encoder.Close(); err != nil {
+			writer.CloseWithError(err)
+			return
+		}
+		_, err := io.WriteString(writer, suffix)
+		writer.CloseWithError(err)
+	}()
+
+	length := int64(-1)
+	if size >= 0 {
+		length = int64(len(prefix)+len(suffix)) + int64(base64.StdEncoding.EncodedLen(int(size)))
+	}
+	return reader, length
+}
+
+// createTree 在基础树上添加或删除文件，返回新树的 SHA
+func createTree(client *GitHubClient, baseTree string, entries []gitTreeEntry) (string, error) {
+	resp, err := client.makeRequest("POST", gitDataURL("trees"), map[string]interface{}{
+		"base_tree": baseTree,
+		"tree":      entries,
+	})
+	if err != nil {
+		return "", err
+	}
+	var tree struct {
+		SHA string `json:"sha"`
+	}
+	if err := client.handleResponse(resp, &tree); err != nil {
+		return "", fmt.Errorf("创建树失败: %v", err)
+	}
+	return tree.SHA, nil
+}
+
+// createCommit 创建提交，返回提交的 SHA
+func createCommit(client *GitHubClient, message, tree, parent string) (string, error) {
+	resp, err := client.makeRequest("POST", gitDataURL("commits"), map[string]interface{}{
+		"message": message,
+		"tree":    tree,
+		"parents": []string{parent},
+	})
+	if err != nil {
+		return "", err
+	}
+	var commit struct {
+		SHA string `json:"sha"`
+	}
+	if err := client.handleResponse(resp, &commit); err != nil {
+		return "", fmt.Errorf("创建提交失败: %v", err)
+	}
+	return commit.SHA, nil
+}
+
+// updateBranch 将分支快进到新提交。分支已被其他提交更新（非快进）时返回 false，由调用方基于新的提交重试
+func updateBranch(client *GitHubClient, branch, commit string) (bool, error) {
+	resp, err := client.makeRequest("PATCH", gitDataURL("refs/heads/"+url.PathEscape(branch)), map[string]interface{}{
+		"sha":   commit,
+		"force": false,
+	})
+	if err != nil {
+		return false, err
+	}
+	if resp.StatusCode == http.StatusUnprocessableEntity || resp.StatusCode == http.StatusConflict {
+		resp.Body.Close()
+		return false, nil
+	}
+	if err := client.handleResponse(resp, nil); err != nil {
+		return false, fmt.Errorf("更新分支 %s 失败: %v", branch, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,50 @@
+package github
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestBase64JSONBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		size    int64
+	}{
+		{"empty", "", 0},
+		{"padding", "ab", 2},
+		{"binary", "\x00\xff\x10moments", 10},
+		{"unknown size", "hello", -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, length := base64JSONBody(`{"encoding":"base64","content":"`, strings.NewReader(tt.content), tt.size, `"}`)
+			body, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("读取请求体失败: %v", err)
+			}
+
+			wantLength := int64(len(body))
+			if tt.size < 0 {
+				wantLength = -1
+			}
+			if length != wantLength {
+				t.Errorf("length = %d, want %d", length, wantLength)
+			}
+
+			var blob struct {
+				Encoding string `json:"encoding"`
+				Content  []byte `json:"content"`
+			}
+			if err := json.Unmarshal(body, &blob); err != nil {
+				t.Fatalf("请求体不是有效的 JSON: %v\n%s", err, body)
+			}
+			if string(blob.Content) != tt.content {
+				t.Errorf("content = %q, want %q", blob.Content, tt.content)
+			}
+		})
+	}
+}
@@ -3,27 +3,68 @@ package github
 import (
 	"fmt"
 	"moments-go/types"
+	"net/url"
 	"strconv"
 	"time"
 	"moments-go/config"
 )
 
+// ListIssuesOptions 动态列表查询参数
+type ListIssuesOptions struct {
+	State     string // open / closed / all，默认 open
+	Label     string // 按标签筛选，为空表示全部
+	Sort      string // created / updated，默认 created
+	Direction string // asc / desc，默认 desc
+	Since     string // 只返回该时间之后更新过的动态，ISO 8601 格式
+	Page      int
+	PerPage   int
+}
+
+// IssuePage 一页动态列表
+type IssuePage struct {
+	Issues []types.GitHubIssueResponse
+	Page   int
+	Links  PageLinks
+}
+
+// HasPrev 是否有上一页
+func (p *IssuePage) HasPrev() bool {
+	return p.Links.Prev > 0
+}
+
+// HasNext 是否有下一页
+func (p *IssuePage) HasNext() bool {
+	return p.Links.Next > 0
+}
+
+// LastPage 最后一页的页码（未知时返回当前页）
+func (p *IssuePage) LastPage() int {
+	if p.Links.Last > 0 {
+		return p.Links.Last
+	}
+	return p.Page
+}
+
 // CreateGitHubIssue 创建 GitHub Issue
 func CreateGitHubIssue(content string) (*types.GitHubIssueResponse, error) {
 	return CreateGitHubIssueWithLabels(content, []string{"动态"})
 }
 
-// CreateGitHubIssueWithLabels 创建带标签的 GitHub Issue
+// CreateGitHubIssueWithLabels 创建带标签的 GitHub Issue，标题为当前时间戳
 func CreateGitHubIssueWithLabels(content string, labels []string) (*types.GitHubIssueResponse, error) {
+	return CreateGitHubIssueWithTitle(strconv.FormatInt(time.Now().Unix(), 10), content, labels)
+}
+
+// CreateGitHubIssueWithTitle 创建指定标题和标签的 GitHub Issue
+func CreateGitHubIssueWithTitle(title, content string, labels []string) (*types.GitHubIssueResponse, error) {
 	if len(content) > 5000 {
 		return nil, fmt.Errorf("内容长度不能超过5000字符")
 	}
 
 	client := NewGitHubClient()
-	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
 	
 	issueData := map[string]interface{}{
-		"title":  timestamp,
+		"title":  title,
 		"body":   content,
 		"labels": labels,
 	}
@@ -86,22 +127,135 @@ func UpdateGitHubIssue(issueNumber int, content string, labels []string) (*types
 	return &issue, nil
 }
 
-// GetRecentIssues 获取最近的动态列表
-func GetRecentIssues(limit int) ([]types.GitHubIssueResponse, error) {
+// UpdateGitHubIssueBody 只更新 Issue 正文，不修改标签
+func UpdateGitHubIssueBody(issueNumber int, body string) (*types.GitHubIssueResponse, error) {
 	client := NewGitHubClient()
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/issues?state=open&per_page=%d&sort=created&direction=desc", config.Cfg.GitHubUsername, config.Cfg.GitHubRepo, limit)
-	
-	resp, err := client.makeRequest("GET", url, nil)
+	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/issues/%d", config.Cfg.GitHubUsername, config.Cfg.GitHubRepo, issueNumber)
+	resp, err := client.makeRequest("PATCH", url, map[string]interface{}{"body": body})
+	if err != nil {
+		return nil, err
+	}
+
+	var issue types.GitHubIssueResponse
+	if err := client.handleResponse(resp, &issue); err != nil {
+		return nil, err
+	}
+
+	return &issue, nil
+}
+
+// ListIssues 分页获取动态列表，使用 Link 响应头翻页
+func ListIssues(opts ListIssuesOptions) (*IssuePage, error) {
+	if opts.State == "" {
+		opts.State = "open"
+	}
+	if opts.Page < 1 {
+		opts.Page = 1
+	}
+	if opts.PerPage < 1 {
+		opts.PerPage = 10
+	}
+	if opts.Sort == "" {
+		opts.Sort = "created"
+	}
+	if opts.Direction == "" {
+		opts.Direction = "desc"
+	}
+
+	params := url.Values{}
+	params.Set("state", opts.State)
+	params.Set("sort", opts.Sort)
+	params.Set("direction", opts.Direction)
+	params.Set("per_page", strconv.Itoa(opts.PerPage))
+	params.Set("page", strconv.Itoa(opts.Page))
+	if opts.Label != "" {
+		params.Set("labels", opts.Label)
+	}
+	if opts.Since != "" {
+		params.Set("since", opts.Since)
+	}
+
+	client := NewGitHubClient()
+	apiURL := fmt.Sprintf("https://api.github.com/repos/%s/%s/issues?%s", config.Cfg.GitHubUsername, config.Cfg.GitHubRepo, params.Encode())
+
+	resp, err := client.makeRequest("GET", apiURL, nil)
 	if err != nil {
 		return nil, err
 	}
+	links := parseLinkHeader(resp.Header.Get("Link"))
 
 	var issues []types.GitHubIssueResponse
 	if err := client.handleResponse(resp, &issues); err != nil {
 		return nil, err
 	}
 
-	return issues, nil
+	return &IssuePage{
+		Issues: filterPullRequests(issues),
+		Page:   opts.Page,
+		Links:  links,
+	}, nil
+}
+
+// ListAllIssues 遍历所有分页，获取指定状态的全部动态
+func ListAllIssues(state string) ([]types.GitHubIssueResponse, error) {
+	var all []types.GitHubIssueResponse
+
+	page := 1
+	for page > 0 {
+		result, err := ListIssues(ListIssuesOptions{State: state, Sort: "created", Page: page, PerPage: 100})
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, result.Issues...)
+		page = result.Links.Next
+	}
+
+	return all, nil
+}
+
+// searchIssues 调用 GitHub 搜索接口，结果按 sort 字段倒序
+func searchIssues(query, sort string, page, perPage int) (*IssuePage, error) {
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("sort", sort)
+	params.Set("order", "desc")
+	params.Set("per_page", strconv.Itoa(perPage))
+	params.Set("page", strconv.Itoa(page))
+
+	client := NewGitHubClient()
+	apiURL := "https://api.github.com/search/issues?" + params.Encode()
+
+	resp, err := client.makeRequest("GET", apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	links := parseLinkHeader(resp.Header.Get("Link"))
+
+	var result struct {
+		TotalCount int                         `json:"total_count"`
+		Items      []types.GitHubIssueResponse `json:"items"`
+	}
+	if err := client.handleResponse(resp, &result); err != nil {
+		return nil, err
+	}
+
+	return &IssuePage{
+		Issues: filterPullRequests(result.Items),
+		Page:   page,
+		Links:  links,
+	}, nil
+}
+
+// filterPullRequests 过滤掉 issues 接口返回的 Pull Request
+func filterPullRequests(issues []types.GitHubIssueResponse) []types.GitHubIssueResponse {
+	filtered := make([]types.GitHubIssueResponse, 0, len(issues))
+	for _, issue := range issues {
+		if issue.IsPullRequest() {
+			continue
+		}
+		filtered = append(filtered, issue)
+	}
+	return filtered
 }
 
 // DeleteGitHubIssue 删除 GitHub Issue
@@ -115,4 +269,29 @@ func DeleteGitHubIssue(issueNumber int) error {
 	}
 
 	return client.handleResponse(resp, nil)
+}
+
+// RestoreGitHubIssue 恢复已删除（关闭）的 GitHub Issue
+func RestoreGitHubIssue(issueNumber int) (*types.GitHubIssueResponse, error) {
+	client := NewGitHubClient()
+	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/issues/%d", config.Cfg.GitHubUsername, config.Cfg.GitHubRepo, issueNumber)
+
+	resp, err := client.makeRequest("PATCH", url, map[string]string{"state": "open"})
+	if err != nil {
+		return nil, err
+	}
+
+	var issue types.GitHubIssueResponse
+	if err := client.handleResponse(resp, &issue); err != nil {
+		return nil, err
+	}
+
+	return &issue, nil
+}
+
+// PurgeGitHubIssue 永久删除 GitHub Issue（REST 接口不支持，需使用 GraphQL deleteIssue）
+func PurgeGitHubIssue(nodeID string) error {
+	client := NewGitHubClient()
+	mutation := `mutation($issueId: ID!) { deleteIssue(input: {issueId: $issueId}) { clientMutationId } }`
+	return client.graphQL(mutation, map[string]interface{}{"issueId": nodeID}, nil)
 } 
@@ -0,0 +1,60 @@
+package github
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// PageLinks Link 响应头中的分页信息（页码为 0 表示不存在）
+type PageLinks struct {
+	First int
+	Prev  int
+	Next  int
+	Last  int
+}
+
+// parseLinkHeader 解析 GitHub 返回的 Link 响应头
+// 格式：<https://api.github.com/...&page=2>; rel="next", <...&page=5>; rel="last"
+func parseLinkHeader(header string) PageLinks {
+	var links PageLinks
+	if header == "" {
+		return links
+	}
+
+	for _, part := range strings.Split(header, ",") {
+		sections := strings.Split(strings.TrimSpace(part), ";")
+		if len(sections) < 2 {
+			continue
+		}
+
+		rawURL := strings.Trim(strings.TrimSpace(sections[0]), "<>")
+		parsed, err := url.Parse(rawURL)
+		if err != nil {
+			continue
+		}
+		page, err := strconv.Atoi(parsed.Query().Get("page"))
+		if err != nil {
+			continue
+		}
+
+		for _, attr := range sections[1:] {
+			attr = strings.TrimSpace(attr)
+			if !strings.HasPrefix(attr, "rel=") {
+				continue
+			}
+			switch strings.Trim(strings.TrimPrefix(attr, "rel="), `"`) {
+			case "first":
+				links.First = page
+			case "prev":
+				links.Prev = page
+			case "next":
+				links.Next = page
+			case "last":
+				links.Last = page
+			}
+		}
+	}
+
+	return links
+}
@@ -0,0 +1,205 @@
+package github
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"moments-go/config"
+	"moments-go/types"
+)
+
+var (
+	// 路径模板中日期使用的时区，首次使用时加载
+	mediaLocation     *time.Location
+	mediaLocationOnce sync.Once
+)
+
+// MediaPath 文件在存储中的路径，由 MEDIA_PATH_TEMPLATE 生成。
+// 没有内容哈希的文件（未经过 storage 直接上传）用 <时间戳>_<文件名> 代替 {{hash}}，模板中已有 {{name}} 时只用时间戳
+func MediaPath(file *types.MediaFile, timestamp string) string {
+	ext := path.Ext(file.Name)
+	name := strings.TrimSuffix(file.Name, ext)
+	ext = strings.ToLower(strings.TrimPrefix(ext, "."))
+
+	hash := file.Hash
+	if hash == "" {
+		hash = timestamp
+		if !strings.Contains(config.Cfg.MediaPathTemplate, "{{name}}") {
+			hash += "_" + name
+		}
+	}
+
+	t := time.Now()
+	if seconds, err := strconv.ParseInt(timestamp, 10, 64); err == nil {
+		t = time.Unix(seconds, 0)
+	}
+	t = t.In(pathLocation())
+
+	template := config.Cfg.MediaPathTemplate
+	if ext == "" {
+		template = strings.ReplaceAll(template, ".{{ext}}", "")
+	}
+	return strings.NewReplacer(
+		"{{year}}", t.Format("2006"),
+		"{{month}}", t.Format("01"),
+		"{{day}}", t.Format("02"),
+		"{{hash}}", hash,
+		"{{ext}}", ext,
+		"{{name}}", name,
+		"{{timestamp}}", timestamp,
+	).Replace(template)
+}
+
+// MediaFileName 上传后的文件名，即路径的最后一段，用作 Release 附件名。
+// 同一个 Release 中按附件名判断是否为同一文件，最后一段不含内容哈希时（如默认模板）在前面加上哈希
+func MediaFileName(file *types.MediaFile, timestamp string) string {
+	name := path.Base(MediaPath(file, timestamp))
+	if file.Hash != "" && !strings.Contains(name, file.Hash) {
+		name = file.Hash + "_" + name
+	}
+	return name
+}
+
+// pathLocation 路径模板使用的时区：TIMEZONE，未设置或无效时使用本地时区
+func pathLocation() *time.Location {
+	mediaLocationOnce.Do(func() {
+		mediaLocation = time.Local
+		if config.Cfg.Timezone != "" {
+			if loc, err := time.LoadLocation(config.Cfg.Timezone); err == nil {
+				mediaLocation = loc
+			}
+		}
+	})
+	return mediaLocation
+}
+
+// IsMediaPath 判断文件仓库中的路径是否为上传的媒体文件：位于 moments/ 目录或符合 MEDIA_PATH_TEMPLATE
+func IsMediaPath(p string) bool {
+	if strings.HasPrefix(p, "moments/") {
+		return true
+	}
+
+	pattern := regexp.QuoteMeta(config.Cfg.MediaPathTemplate)
+	pattern = strings.ReplaceAll(pattern, regexp.QuoteMeta(".{{ext}}"), `(?:\.[^/]*)?`)
+	pattern = strings.NewReplacer(
+		regexp.QuoteMeta("{{year}}"), `\d{4}`,
+		regexp.QuoteMeta("{{month}}"), `\d{2}`,
+		regexp.QuoteMeta("{{day}}"), `\d{2}`,
+		regexp.QuoteMeta("{{hash}}"), `[^/]+`,
+		regexp.QuoteMeta("{{ext}}"), `[^/]*`,
+		regexp.QuoteMeta("{{name}}"), `[^/]+`,
+		regexp.QuoteMeta("{{timestamp}}"), `\d+`,
+	).Replace(pattern)
+	matched, _ := regexp.MatchString("^"+pattern+"$", p)
+	return matched
+}
+
+// RepoFileURL 文件仓库中文件的访问地址：设置了 MEDIA_URL_TEMPLATE 时按模板生成（如 jsDelivr 或自定义域名），否则为 raw 地址
+func RepoFileURL(repo, branch, p string) string {
+	if config.Cfg.MediaURLTemplate == "" {
+		return rawFileURL(repo, branch, p)
+	}
+
+	segments := strings.Split(p, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.NewReplacer(
+		"{{owner}}", config.Cfg.GitHubUsername,
+		"{{repo}}", repo,
+		"{{branch}}", branch,
+		"{{path}}", strings.Join(segments, "/"),
+	).Replace(config.Cfg.MediaURLTemplate)
+}
+
+// repoURLPatterns 匹配文件仓库中文件地址的正则表达式，第一个分组为文件路径
+func repoURLPatterns(repo string) []*regexp.Regexp {
+	owner := regexp.QuoteMeta(config.Cfg.GitHubUsername)
+	repo = regexp.QuoteMeta(repo)
+	patterns := []*regexp.Regexp{
+		regexp.MustCompile(fmt.Sprintf(`(?i)https://raw\.githubusercontent\.com/%s/%s/[^/\s]+/([^\s)"'<>]+)`, owner, repo)),
+	}
+
+	if config.Cfg.MediaURLTemplate != "" {
+		pattern := strings.NewReplacer(
+			regexp.QuoteMeta("{{owner}}"), owner,
+			regexp.QuoteMeta("{{repo}}"), repo,
+			regexp.QuoteMeta("{{branch}}"), `[^/\s]+`,
+			regexp.QuoteMeta("{{path}}"), `([^\s)"'<>]+)`,
+		).Replace(regexp.QuoteMeta(config.Cfg.MediaURLTemplate))
+		if compiled, err := regexp.Compile("(?i)" + pattern); err == nil {
+			patterns = append(patterns, compiled)
+		}
+	}
+	return patterns
+}
+
+// extractRepoPaths 提取内容中指定文件仓库的文件路径
+func extractRepoPaths(body, repo string) []string {
+	seen := make(map[string]bool)
+	var paths []string
+	for _, pattern := range repoURLPatterns(repo) {
+		for _, match := range pattern.FindAllStringSubmatch(body, -1) {
+			p, err := url.PathUnescape(match[1])
+			if err != nil {
+				p = match[1]
+			}
+			if seen[p] {
+				continue
+			}
+			seen[p] = true
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// ExtractRepoFiles 提取动态内容中引用的所有文件仓库（包括切换前的旧仓库）中的文件和 Release 附件
+func ExtractRepoFiles(body string) []types.UploadedFile {
+	var files []types.UploadedFile
+	for _, repo := range config.GetFileRepos() {
+		for _, p := range extractRepoPaths(body, repo) {
+			files = append(files, types.UploadedFile{Path: p, Repo: repo})
+		}
+		files = append(files, extractReleaseAssets(body, repo)...)
+	}
+	return files
+}
+
+// RewriteRepoURLs 将内容中文件仓库（包括切换前的旧仓库）的文件地址改写为当前 MEDIA_URL_TEMPLATE 生成的地址，
+// 返回改写后的内容和改写的地址数
+func RewriteRepoURLs(body string) (string, int, error) {
+	client := NewGitHubClient()
+	count := 0
+	for _, repo := range config.GetFileRepos() {
+		patterns := repoURLPatterns(repo)
+		if !patterns[0].MatchString(body) && (len(patterns) == 1 || !patterns[1].MatchString(body)) {
+			continue
+		}
+
+		branch, err := getRepoBranch(client, repo)
+		if err != nil {
+			return "", 0, err
+		}
+		for _, pattern := range patterns {
+			body = pattern.ReplaceAllStringFunc(body, func(match string) string {
+				p := pattern.FindStringSubmatch(match)[1]
+				if unescaped, err := url.PathUnescape(p); err == nil {
+					p = unescaped
+				}
+				rewritten := RepoFileURL(repo, branch, p)
+				if rewritten != match {
+					count++
+				}
+				return rewritten
+			})
+		}
+	}
+	return body, count, nil
+}
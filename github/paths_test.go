@@ -0,0 +1,210 @@
+package github
+
+import (
+	"reflect"
+	"testing"
+
+	"moments-go/config"
+	"moments-go/types"
+)
+
+// 2023-12-31 16:00 UTC，即 Asia/Shanghai 的 2024-01-01 00:00
+const testTimestamp = "1704038400"
+
+const yearMonthTemplate = "{{year}}/{{month}}/{{hash}}.{{ext}}"
+
+// setPathConfig 设置路径模板、地址模板和文件仓库，测试结束后恢复
+func setPathConfig(t *testing.T, pathTemplate, urlTemplate string) {
+	t.Helper()
+	saved := config.Cfg
+	current, previous := config.GetFileRepo(), config.PreviousFileRepos
+	t.Cleanup(func() {
+		config.Cfg = saved
+		config.SetFileRepos(current, previous)
+	})
+
+	config.Cfg.GitHubUsername = "alice"
+	config.Cfg.Timezone = "Asia/Shanghai"
+	config.Cfg.MediaPathTemplate = pathTemplate
+	config.Cfg.MediaURLTemplate = urlTemplate
+	config.SetFileRepos("files-2024", []string{"files"})
+
+	repoBranchMutex.Lock()
+	repoBranches["files"] = "main"
+	repoBranches["files-2024"] = "master"
+	repoBranchMutex.Unlock()
+}
+
+func TestMediaPath(t *testing.T) {
+	tests := []struct {
+		template string
+		file     types.MediaFile
+		want     string
+	}{
+		{config.DefaultMediaPathTemplate, types.MediaFile{Name: "Photo.JPG", Hash: "abc"}, "moments/abc/Photo.jpg"},
+		{config.DefaultMediaPathTemplate, types.MediaFile{Name: "README", Hash: "abc"}, "moments/abc/README"},
+		{config.DefaultMediaPathTemplate, types.MediaFile{Name: "photo.jpg"}, "moments/1704038400/photo.jpg"},
+		{yearMonthTemplate, types.MediaFile{Name: "video.MP4", Hash: "def"}, "2024/01/def.mp4"},
+		{yearMonthTemplate, types.MediaFile{Name: "README", Hash: "def"}, "2024/01/def"},
+		{yearMonthTemplate, types.MediaFile{Name: "photo.jpg"}, "2024/01/1704038400_photo.jpg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template+"/"+tt.file.Name, func(t *testing.T) {
+			setPathConfig(t, tt.template, "")
+			if got := MediaPath(&tt.file, testTimestamp); got != tt.want {
+				t.Errorf("MediaPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMediaFileName(t *testing.T) {
+	tests := []struct {
+		template string
+		file     types.MediaFile
+		want     string
+	}{
+		{config.DefaultMediaPathTemplate, types.MediaFile{Name: "Movie.MP4", Hash: "abc"}, "abc_Movie.mp4"},
+		{yearMonthTemplate, types.MediaFile{Name: "movie.mp4", Hash: "def"}, "def.mp4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template+"/"+tt.file.Name, func(t *testing.T) {
+			setPathConfig(t, tt.template, "")
+			if got := MediaFileName(&tt.file, testTimestamp); got != tt.want {
+				t.Errorf("MediaFileName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsMediaPath(t *testing.T) {
+	tests := []struct {
+		template string
+		path     string
+		want     bool
+	}{
+		{config.DefaultMediaPathTemplate, "moments/abc/Photo.jpg", true},
+		{config.DefaultMediaPathTemplate, "moments/abc.jpg", true},
+		{config.DefaultMediaPathTemplate, "moments/abc", true},
+		{config.DefaultMediaPathTemplate, "README.md", false},
+		{config.DefaultMediaPathTemplate, "2024/01/abc.jpg", false},
+		{yearMonthTemplate, "2024/01/abc.jpg", true},
+		{yearMonthTemplate, "2024/01/abc", true},
+		{yearMonthTemplate, "moments/abc.jpg", true},
+		{yearMonthTemplate, "2024/1/abc.jpg", false},
+		{yearMonthTemplate, "2024/01/sub/abc.jpg", false},
+		{yearMonthTemplate, "docs/readme.md", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template+"/"+tt.path, func(t *testing.T) {
+			setPathConfig(t, tt.template, "")
+			if got := IsMediaPath(tt.path); got != tt.want {
+				t.Errorf("IsMediaPath(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractMediaPaths(t *testing.T) {
+	tests := []struct {
+		name        string
+		urlTemplate string
+		body        string
+		want        []string
+	}{
+		{
+			name: "raw",
+			body: `<img src="https://raw.githubusercontent.com/alice/files-2024/master/2024/01/abc.jpg">` +
+				"\nhttps://raw.githubusercontent.com/alice/files-2024/master/moments/a%20b",
+			want: []string{"2024/01/abc.jpg", "moments/a b"},
+		},
+		{
+			name:        "cdn",
+			urlTemplate: config.JSDelivrURLTemplate,
+			body: `<video src="https://cdn.jsdelivr.net/gh/alice/files-2024@master/2024/01/def.mp4"></video>` +
+				"\nhttps://raw.githubusercontent.com/alice/files-2024/master/2024/01/abc.jpg",
+			want: []string{"2024/01/abc.jpg", "2024/01/def.mp4"},
+		},
+		{
+			name: "cdn template not configured",
+			body: "https://cdn.jsdelivr.net/gh/alice/files-2024@master/2024/01/def.mp4",
+			want: nil,
+		},
+		{
+			name: "other repos",
+			body: "https://raw.githubusercontent.com/alice/files/main/2024/01/old.jpg\n" +
+				"https://raw.githubusercontent.com/bob/files-2024/master/2024/01/abc.jpg",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setPathConfig(t, yearMonthTemplate, tt.urlTemplate)
+			if got := ExtractMediaPaths(tt.body); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExtractMediaPaths() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRewriteRepoURLs(t *testing.T) {
+	tests := []struct {
+		name        string
+		urlTemplate string
+		body        string
+		want        string
+		count       int
+	}{
+		{
+			name:        "raw to cdn",
+			urlTemplate: config.JSDelivrURLTemplate,
+			body:        `<img src="https://raw.githubusercontent.com/alice/files-2024/master/2024/01/abc.jpg">`,
+			want:        `<img src="https://cdn.jsdelivr.net/gh/alice/files-2024@master/2024/01/abc.jpg">`,
+			count:       1,
+		},
+		{
+			name:        "previous repo without extension",
+			urlTemplate: "https://img.example.com/{{repo}}/{{path}}",
+			body:        "https://raw.githubusercontent.com/alice/files/main/moments/a%20b",
+			want:        "https://img.example.com/files/moments/a%20b",
+			count:       1,
+		},
+		{
+			name:        "already rewritten",
+			urlTemplate: config.JSDelivrURLTemplate,
+			body:        "https://cdn.jsdelivr.net/gh/alice/files-2024@master/2024/01/abc.jpg",
+			want:        "https://cdn.jsdelivr.net/gh/alice/files-2024@master/2024/01/abc.jpg",
+			count:       0,
+		},
+		{
+			name:  "raw without template",
+			body:  "https://raw.githubusercontent.com/alice/files-2024/master/2024/01/abc.jpg",
+			want:  "https://raw.githubusercontent.com/alice/files-2024/master/2024/01/abc.jpg",
+			count: 0,
+		},
+		{
+			name:        "other owner",
+			urlTemplate: config.JSDelivrURLTemplate,
+			body:        "https://raw.githubusercontent.com/bob/files-2024/master/2024/01/abc.jpg",
+			want:        "https://raw.githubusercontent.com/bob/files-2024/master/2024/01/abc.jpg",
+			count:       0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setPathConfig(t, yearMonthTemplate, tt.urlTemplate)
+			got, count, err := RewriteRepoURLs(tt.body)
+			if err != nil {
+				t.Fatalf("RewriteRepoURLs() error = %v", err)
+			}
+			if got != tt.want || count != tt.count {
+				t.Errorf("RewriteRepoURLs() = %q, %d, want %q, %d", got, count, tt.want, tt.count)
+			}
+		})
+	}
+}
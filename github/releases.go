@@ -0,0 +1,220 @@
+package github
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"moments-go/config"
+	"moments-go/types"
+)
+
+// githubRelease GitHub Release 信息
+type githubRelease struct {
+	ID      int64                `json:"id"`
+	TagName string               `json:"tag_name"`
+	Assets  []githubReleaseAsset `json:"assets"`
+}
+
+// githubReleaseAsset Release 附件信息
+type githubReleaseAsset struct {
+	ID                 int64  `json:"id"`
+	Name               string `json:"name"`
+	State              string `json:"state"` // uploaded，上传中断的附件为 starter
+	BrowserDownloadURL string `json:"browser_download_url"`
+}
+
+// 避免并发上传时重复创建同一个月的 Release
+var releaseMutex sync.Mutex
+
+// MediaReleaseTag 当月媒体 Release 的标签，每月一个 Release
+func MediaReleaseTag(t time.Time) string {
+	return "media-" + t.Format("2006-01")
+}
+
+// UploadReleaseAsset 将文件以流的形式上传为当月媒体 Release 的附件，适合超过 contents 接口限制的大视频
+func UploadReleaseAsset(name, contentType string, content io.Reader, size int64) (*types.UploadedFile, error) {
+	client := NewGitHubClient()
+	tag := MediaReleaseTag(time.Now())
+
+	release, err := getOrCreateRelease(client, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	uploadURL := fmt.Sprintf("https://uploads.github.com/repos/%s/%s/releases/%d/assets?name=%s",
+		config.Cfg.GitHubUsername, config.GetFileRepo(), release.ID, url.QueryEscape(name))
+	resp, err := client.makeStreamRequest("POST", uploadURL, contentType, content, size)
+	if err != nil {
+		return nil, err
+	}
+
+	var asset githubReleaseAsset
+	if resp.StatusCode == http.StatusUnprocessableEntity {
+		// 附件以内容哈希命名，同名附件已存在（如哈希索引中的记录已丢失）时内容相同，直接使用已有的附件
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if !strings.Contains(string(body), "already_exists") {
+			return nil, fmt.Errorf("上传 Release 附件 %s 失败: GitHub API 请求失败，状态码: %d, 响应: %s", name, resp.StatusCode, string(body))
+		}
+		existing, err := findReleaseAsset(client, tag, name)
+		if err != nil {
+			return nil, err
+		}
+		asset = *existing
+	} else if err := client.handleResponse(resp, &asset); err != nil {
+		return nil, fmt.Errorf("上传 Release 附件 %s 失败: %v", name, err)
+	}
+
+	return &types.UploadedFile{Path: asset.Name, URL: asset.BrowserDownloadURL, Release: tag, Repo: config.GetFileRepo()}, nil
+}
+
+// findReleaseAsset 查找 Release 中已存在的同名附件。上次上传中断留下的附件会被删除并返回错误，由调用方重新上传
+func findReleaseAsset(client *GitHubClient, tag, name string) (*githubReleaseAsset, error) {
+	release, err := getRelease(client, config.GetFileRepo(), tag)
+	if err != nil {
+		return nil, err
+	}
+	if release == nil {
+		return nil, fmt.Errorf("Release %s 不存在", tag)
+	}
+
+	for _, asset := range release.Assets {
+		if asset.Name != name {
+			continue
+		}
+		if asset.State != "uploaded" {
+			resp, err := client.makeRequest("DELETE", releasesURL(config.GetFileRepo(), fmt.Sprintf("assets/%d", asset.ID)), nil)
+			if err != nil {
+				return nil, err
+			}
+			if err := client.handleResponse(resp, nil); err != nil {
+				return nil, fmt.Errorf("删除未上传完成的附件 %s 失败: %v", name, err)
+			}
+			return nil, fmt.Errorf("附件 %s 上次未上传完成，已删除，请重新上传", name)
+		}
+		return &asset, nil
+	}
+	return nil, fmt.Errorf("Release %s 中没有附件 %s", tag, name)
+}
+
+// getOrCreateRelease 获取指定标签的 Release，不存在时创建
+func getOrCreateRelease(client *GitHubClient, tag string) (*githubRelease, error) {
+	releaseMutex.Lock()
+	defer releaseMutex.Unlock()
+
+	release, err := getRelease(client, config.GetFileRepo(), tag)
+	if err != nil || release != nil {
+		return release, err
+	}
+
+	resp, err := client.makeRequest("POST", releasesURL(config.GetFileRepo(), ""), map[string]interface{}{
+		"tag_name": tag,
+		"name":     fmt.Sprintf("Media %s", tag[len("media-"):]),
+		"body":     "动态中的大文件（视频等），由 moments 机器人自动创建。",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var created githubRelease
+	if err := client.handleResponse(resp, &created); err != nil {
+		return nil, fmt.Errorf("创建 Release %s 失败: %v", tag, err)
+	}
+	return &created, nil
+}
+
+// getRelease 获取文件仓库中指定标签的 Release，不存在时返回 nil
+func getRelease(client *GitHubClient, repo, tag string) (*githubRelease, error) {
+	resp, err := client.makeRequest("GET", releasesURL(repo, "tags/"+url.PathEscape(tag)), nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, nil
+	}
+
+	var release githubRelease
+	if err := client.handleResponse(resp, &release); err != nil {
+		return nil, fmt.Errorf("获取 Release %s 失败: %v", tag, err)
+	}
+	return &release, nil
+}
+
+// DeleteReleaseAsset 删除文件仓库 Release 中的附件
+func DeleteReleaseAsset(repo, tag, name string) error {
+	client := NewGitHubClient()
+
+	release, err := getRelease(client, repo, tag)
+	if err != nil {
+		return err
+	}
+	if release == nil {
+		return fmt.Errorf("Release %s 不存在", tag)
+	}
+
+	for _, asset := range release.Assets {
+		if asset.Name != name {
+			continue
+		}
+		resp, err := client.makeRequest("DELETE", releasesURL(repo, fmt.Sprintf("assets/%d", asset.ID)), nil)
+		if err != nil {
+			return err
+		}
+		return client.handleResponse(resp, nil)
+	}
+
+	return fmt.Errorf("Release %s 中没有附件 %s", tag, name)
+}
+
+// DeleteUploadedFile 删除上传到文件仓库的文件或 Release 附件
+func DeleteUploadedFile(file types.UploadedFile) error {
+	repo := file.Repo
+	if repo == "" {
+		repo = config.GetFileRepo()
+	}
+	if file.Release != "" {
+		return DeleteReleaseAsset(repo, file.Release, file.Path)
+	}
+	return DeleteFileFromGitHub(repo, file.Path)
+}
+
+// extractReleaseAssets 提取动态内容中引用的文件仓库 Release 附件
+func extractReleaseAssets(body, repo string) []types.UploadedFile {
+	pattern := regexp.MustCompile(fmt.Sprintf(`(?i)https://github\.com/%s/%s/releases/download/([^/\s]+)/([^\s)"'<>]+)`,
+		regexp.QuoteMeta(config.Cfg.GitHubUsername), regexp.QuoteMeta(repo)))
+
+	seen := make(map[string]bool)
+	var assets []types.UploadedFile
+	for _, match := range pattern.FindAllStringSubmatch(body, -1) {
+		if seen[match[0]] {
+			continue
+		}
+		seen[match[0]] = true
+		tag, err := url.PathUnescape(match[1])
+		if err != nil {
+			tag = match[1]
+		}
+		name, err := url.PathUnescape(match[2])
+		if err != nil {
+			name = match[2]
+		}
+		assets = append(assets, types.UploadedFile{Path: name, URL: match[0], Release: tag, Repo: repo})
+	}
+	return assets
+}
+
+// releasesURL 生成文件仓库 Release 接口地址
+func releasesURL(repo, path string) string {
+	base := fmt.Sprintf("https://api.github.com/repos/%s/%s/releases", config.Cfg.GitHubUsername, repo)
+	if path == "" {
+		return base
+	}
+	return base + "/" + path
+}
@@ -0,0 +1,128 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"moments-go/config"
+)
+
+var (
+	// 文件仓库的默认分支，首次使用时获取，仓库名 -> 分支
+	repoBranches    = make(map[string]string)
+	repoBranchMutex sync.Mutex
+)
+
+// repoInfo 仓库信息
+type repoInfo struct {
+	DefaultBranch string `json:"default_branch"`
+	Size          int64  `json:"size"` // 单位 KB
+	Private       bool   `json:"private"`
+}
+
+// repoURL 生成仓库接口地址
+func repoURL(repo string) string {
+	return fmt.Sprintf("https://api.github.com/repos/%s/%s", config.Cfg.GitHubUsername, repo)
+}
+
+// getRepoInfo 获取仓库信息，仓库不存在时返回 nil
+func getRepoInfo(client *GitHubClient, repo string) (*repoInfo, error) {
+	resp, err := client.makeRequest("GET", repoURL(repo), nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, nil
+	}
+
+	var info repoInfo
+	if err := client.handleResponse(resp, &info); err != nil {
+		return nil, fmt.Errorf("获取仓库 %s 失败: %v", repo, err)
+	}
+	return &info, nil
+}
+
+// getRepoBranch 获取仓库的默认分支
+func getRepoBranch(client *GitHubClient, repo string) (string, error) {
+	repoBranchMutex.Lock()
+	defer repoBranchMutex.Unlock()
+
+	if branch, exists := repoBranches[repo]; exists {
+		return branch, nil
+	}
+
+	info, err := getRepoInfo(client, repo)
+	if err != nil {
+		return "", err
+	}
+	if info == nil {
+		return "", fmt.Errorf("文件仓库 %s 不存在", repo)
+	}
+	if info.DefaultBranch == "" {
+		return "", fmt.Errorf("文件仓库 %s 没有默认分支，请先创建一个提交", repo)
+	}
+
+	repoBranches[repo] = info.DefaultBranch
+	return info.DefaultBranch, nil
+}
+
+// GetFileRepoSize 获取当前文件仓库的大小（字节）。GitHub 统计的大小会有一定延迟
+func GetFileRepoSize() (int64, error) {
+	info, err := getRepoInfo(NewGitHubClient(), config.GetFileRepo())
+	if err != nil {
+		return 0, err
+	}
+	if info == nil {
+		return 0, fmt.Errorf("文件仓库 %s 不存在", config.GetFileRepo())
+	}
+	return info.Size * 1024, nil
+}
+
+// RepoExists 检查仓库是否存在
+func RepoExists(repo string) (bool, error) {
+	info, err := getRepoInfo(NewGitHubClient(), repo)
+	return info != nil, err
+}
+
+// CreateFileRepo 创建新的文件仓库，可见性与当前文件仓库相同，并创建初始提交以便使用 Git Data API
+func CreateFileRepo(name string) error {
+	client := NewGitHubClient()
+
+	current, err := getRepoInfo(client, config.GetFileRepo())
+	if err != nil {
+		return err
+	}
+	private := current != nil && current.Private
+
+	// 所有者是组织时需要在组织下创建
+	resp, err := client.makeRequest("GET", fmt.Sprintf("https://api.github.com/users/%s", config.Cfg.GitHubUsername), nil)
+	if err != nil {
+		return err
+	}
+	var owner struct {
+		Type string `json:"type"`
+	}
+	if err := client.handleResponse(resp, &owner); err != nil {
+		return fmt.Errorf("获取仓库所有者失败: %v", err)
+	}
+	createURL := "https://api.github.com/user/repos"
+	if owner.Type == "Organization" {
+		createURL = fmt.Sprintf("https://api.github.com/orgs/%s/repos", config.Cfg.GitHubUsername)
+	}
+
+	resp, err = client.makeRequest("POST", createURL, map[string]interface{}{
+		"name":        name,
+		"description": "动态中的媒体文件，由 moments 机器人自动创建。",
+		"private":     private,
+		"auto_init":   true,
+	})
+	if err != nil {
+		return err
+	}
+	if err := client.handleResponse(resp, nil); err != nil {
+		return fmt.Errorf("创建文件仓库 %s 失败: %v", name, err)
+	}
+	return nil
+}
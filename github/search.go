@@ -0,0 +1,58 @@
+package github
+
+import (
+	"fmt"
+	"time"
+
+	"moments-go/config"
+)
+
+// SearchOptions 动态搜索参数
+type SearchOptions struct {
+	Keywords string // 搜索关键词，匹配标题和内容
+	Label    string // 按标签筛选
+	After    string // 创建时间不早于该日期，格式 2006-01-02
+	Before   string // 创建时间早于该日期，格式 2006-01-02
+	Page     int
+	PerPage  int
+}
+
+// SearchMoments 使用 GitHub 搜索接口在动态仓库中搜索
+func SearchMoments(opts SearchOptions) (*IssuePage, error) {
+	if opts.Page < 1 {
+		opts.Page = 1
+	}
+	if opts.PerPage < 1 {
+		opts.PerPage = 10
+	}
+
+	query := fmt.Sprintf("repo:%s/%s is:issue state:open in:title,body", config.Cfg.GitHubUsername, config.Cfg.GitHubRepo)
+	if opts.Keywords != "" {
+		query = opts.Keywords + " " + query
+	}
+	if opts.Label != "" {
+		query += fmt.Sprintf(` label:"%s"`, opts.Label)
+	}
+	if created := createdQualifier(opts.After, opts.Before); created != "" {
+		query += " " + created
+	}
+
+	return searchIssues(query, "created", opts.Page, opts.PerPage)
+}
+
+// createdQualifier 生成搜索接口的创建时间限定条件（after 包含当天，before 不包含当天）
+func createdQualifier(after, before string) string {
+	switch {
+	case after != "" && before != "":
+		end, err := time.Parse("2006-01-02", before)
+		if err != nil {
+			return ""
+		}
+		return fmt.Sprintf("created:%s..%s", after, end.AddDate(0, 0, -1).Format("2006-01-02"))
+	case after != "":
+		return "created:>=" + after
+	case before != "":
+		return "created:<" + before
+	}
+	return ""
+}
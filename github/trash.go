@@ -0,0 +1,31 @@
+package github
+
+import (
+	"time"
+
+	"moments-go/types"
+)
+
+// ListExpiredMoments 列出回收站中关闭时间超过 retentionDays 天的动态
+func ListExpiredMoments(retentionDays int) ([]types.GitHubIssueResponse, error) {
+	if retentionDays <= 0 {
+		return nil, nil
+	}
+
+	closed, err := ListAllIssues("closed")
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -retentionDays)
+	var expired []types.GitHubIssueResponse
+	for _, issue := range closed {
+		closedAt, err := time.Parse(time.RFC3339, issue.ClosedAt)
+		if err != nil || closedAt.After(cutoff) {
+			continue
+		}
+		expired = append(expired, issue)
+	}
+
+	return expired, nil
+}
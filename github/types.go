@@ -1,9 +1,19 @@
 package github
 
+import "moments-go/types"
+
 // GitHubLabel GitHub 标签结构
 type GitHubLabel struct {
 	ID          int    `json:"id"`
 	Name        string `json:"name"`
 	Color       string `json:"color"`
 	Description string `json:"description"`
-} 
+}
+
+// UploadedMedia 已上传的媒体文件及其地址
+type UploadedMedia struct {
+	File         *types.MediaFile
+	URL          string
+	ThumbnailURL string // 封面缩略图地址，没有时为空
+}
+
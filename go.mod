@@ -6,3 +6,7 @@ require (
 	github.com/go-telegram-bot-api/telegram-bot-api/v5 v5.5.1
 	github.com/joho/godotenv v1.4.0
 )
+
+require golang.org/x/image v0.23.0
+
+require golang.org/x/text v0.21.0 // indirect
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"moments-go/config"
+	"moments-go/github"
+	"moments-go/media"
+	"moments-go/types"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// HandleAnimationMessage 处理 GIF 动画消息（Telegram 会将 GIF 转为 MP4）
+func HandleAnimationMessage(bot *tgbotapi.BotAPI, update tgbotapi.Update) error {
+	if !config.IsAuthorizedUser(update.Message.Chat.ID) {
+		return nil
+	}
+	animation := update.Message.Animation
+	if animation == nil {
+		return nil
+	}
+	if int64(animation.FileSize) > fileSizeLimit() {
+		return safeSendMessage(bot, update.Message.Chat.ID, fmt.Sprintf("❌ 动图文件过大，请上传小于 %s 的动图", formatSize(fileSizeLimit())))
+	}
+
+	pending := &types.PendingMedia{
+		FileID:       animation.FileID,
+		FileUniqueID: animation.FileUniqueID,
+		Type:         "animation",
+		Caption:      update.Message.Caption,
+		Labels:       []string{},
+		MimeType:     animation.MimeType,
+		Duration:     animation.Duration,
+		Width:        animation.Width,
+		Height:       animation.Height,
+	}
+	if animation.Thumbnail != nil {
+		pending.ThumbFileID = animation.Thumbnail.FileID
+	}
+
+	return receivePendingMedia(bot, update, pending, "🎞️ 动图已接收！")
+}
+
+// HandleStickerMessage 处理贴纸消息
+func HandleStickerMessage(bot *tgbotapi.BotAPI, update tgbotapi.Update) error {
+	if !config.IsAuthorizedUser(update.Message.Chat.ID) {
+		return nil
+	}
+	sticker := update.Message.Sticker
+	if sticker == nil {
+		return nil
+	}
+
+	pending := &types.PendingMedia{
+		FileID:       sticker.FileID,
+		FileUniqueID: sticker.FileUniqueID,
+		Type:         "sticker",
+		Labels:       []string{},
+		Emoji:        sticker.Emoji,
+		Width:        sticker.Width,
+		Height:       sticker.Height,
+	}
+	// 静态贴纸会转换为 PNG，只有动画贴纸需要静态缩略图作为后备
+	if sticker.Thumbnail != nil {
+		pending.ThumbFileID = sticker.Thumbnail.FileID
+	}
+
+	title := "🧩 贴纸已接收！"
+	if sticker.Emoji != "" {
+		title += " " + sticker.Emoji
+	}
+	return receivePendingMedia(bot, update, pending, title)
+}
+
+// convertSticker 根据贴纸的真实格式处理：静态 WebP 转为 PNG，WebM 视频贴纸循环播放，TGS 动画贴纸保留原格式
+func convertSticker(file *types.MediaFile) {
+	base := strings.TrimSuffix(file.Name, filepath.Ext(file.Name))
+
+	switch file.Type {
+	case "image/webp":
+		converted, err := media.WebPToPNG(file.Content)
+		if err != nil {
+			// 转换失败时保留 WebP 原图
+			log.Printf("转换贴纸失败: %v", err)
+			return
+		}
+		file.Content = converted
+		file.Type = "image/png"
+		file.Name = base + ".png"
+		// 静态贴纸不需要缩略图
+		file.Thumbnail = nil
+	case "video/webm":
+		file.Loop = true
+	case "application/x-gzip", "application/gzip":
+		file.Type = github.TGSStickerType
+		file.Name = base + ".tgs"
+	}
+}
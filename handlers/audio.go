@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"moments-go/config"
+	"moments-go/github"
+	"moments-go/types"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// HandleVoiceMessage 处理语音消息
+func HandleVoiceMessage(bot *tgbotapi.BotAPI, update tgbotapi.Update) error {
+	if !config.IsAuthorizedUser(update.Message.Chat.ID) {
+		return nil
+	}
+	voice := update.Message.Voice
+	if voice == nil {
+		return nil
+	}
+	if int64(voice.FileSize) > fileSizeLimit() {
+		return safeSendMessage(bot, update.Message.Chat.ID, fmt.Sprintf("❌ 语音文件过大，请上传小于 %s 的语音", formatSize(fileSizeLimit())))
+	}
+
+	pending := &types.PendingMedia{
+		FileID:       voice.FileID,
+		FileUniqueID: voice.FileUniqueID,
+		Type:         "voice",
+		Caption:      update.Message.Caption,
+		Labels:       []string{},
+		MimeType:     voice.MimeType,
+		Duration:     voice.Duration,
+	}
+
+	title := fmt.Sprintf("🎙️ 语音已接收！（%s）", github.FormatDuration(voice.Duration))
+	return receivePendingMedia(bot, update, pending, title)
+}
+
+// HandleAudioMessage 处理音频消息
+func HandleAudioMessage(bot *tgbotapi.BotAPI, update tgbotapi.Update) error {
+	if !config.IsAuthorizedUser(update.Message.Chat.ID) {
+		return nil
+	}
+	audio := update.Message.Audio
+	if audio == nil {
+		return nil
+	}
+	if int64(audio.FileSize) > fileSizeLimit() {
+		return safeSendMessage(bot, update.Message.Chat.ID, fmt.Sprintf("❌ 音频文件过大，请上传小于 %s 的音频", formatSize(fileSizeLimit())))
+	}
+
+	pending := &types.PendingMedia{
+		FileID:       audio.FileID,
+		FileUniqueID: audio.FileUniqueID,
+		Type:         "audio",
+		Caption:      update.Message.Caption,
+		Labels:       []string{},
+		FileName:     audio.FileName,
+		MimeType:     audio.MimeType,
+		Duration:     audio.Duration,
+		Performer:    audio.Performer,
+		Title:        audio.Title,
+	}
+	if audio.Thumbnail != nil {
+		pending.ThumbFileID = audio.Thumbnail.FileID
+	}
+
+	title := "🎵 音频已接收！"
+	if description := describeAudio(pending); description != "" {
+		title += "\n\n" + description
+	}
+	return receivePendingMedia(bot, update, pending, title)
+}
+
+// describeAudio 生成音频的简短描述：标题 - 表演者
+func describeAudio(pending *types.PendingMedia) string {
+	var parts []string
+	if pending.Title != "" {
+		parts = append(parts, pending.Title)
+	}
+	if pending.Performer != "" {
+		parts = append(parts, pending.Performer)
+	}
+	return strings.Join(parts, " - ")
+}
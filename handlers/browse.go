@@ -0,0 +1,382 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"moments-go/config"
+	"moments-go/github"
+	"moments-go/store"
+	"moments-go/types"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	browsePageSize  = 5                // 每页显示的动态数量
+	browseMonthSpan = 12               // 月份筛选可选的最近月份数量
+	browseSyncAge   = 30 * time.Second // 浏览前索引允许的最长未同步时间
+)
+
+// HandleListCommand 处理 /list 命令
+func HandleListCommand(bot *tgbotapi.BotAPI, update tgbotapi.Update) error {
+	if !config.IsAuthorizedUser(update.Message.Chat.ID) {
+		return nil
+	}
+	return openMomentBrowser(bot, update.Message.Chat.ID)
+}
+
+// openMomentBrowser 重置筛选条件并发送新的动态浏览器消息
+func openMomentBrowser(bot *tgbotapi.BotAPI, chatID int64) error {
+	config.SetBrowseState(chatID, config.BrowseState{Page: 1})
+	return showMomentBrowser(bot, chatID, 0)
+}
+
+// showMomentBrowser 显示动态浏览器，messageID 不为 0 时编辑原消息
+func showMomentBrowser(bot *tgbotapi.BotAPI, chatID int64, messageID int) error {
+	state := config.GetBrowseState(chatID)
+
+	page, err := fetchBrowsePage(state)
+	if err != nil {
+		return sendOrEditMessage(bot, chatID, messageID, fmt.Sprintf("❌ 获取动态列表失败：%v", err), nil)
+	}
+
+	text := buildBrowserText(state, page)
+	keyboard := buildBrowserKeyboard(state, page)
+	return sendOrEditMessage(bot, chatID, messageID, text, &keyboard)
+}
+
+// fetchBrowsePage 根据浏览状态获取一页动态，浏览模式下使用本地索引，搜索模式下使用搜索接口。
+// 索引尚未完成首次同步或同步失败时，直接从 GitHub 按 Link 响应头分页获取
+func fetchBrowsePage(state config.BrowseState) (*store.Page, error) {
+	if state.Search {
+		return searchMoments(state)
+	}
+
+	if !store.Ready() {
+		// 首次同步在后台进行，完成前索引不完整
+		return fetchGitHubPage(state)
+	}
+	if err := store.SyncIfStale(browseSyncAge); err != nil {
+		log.Printf("同步动态索引失败: %v，直接从 GitHub 获取", err)
+		return fetchGitHubPage(state)
+	}
+
+	moments := store.Query(func(moment *types.PublishedMoment) bool {
+		if moment.State != "open" {
+			return false
+		}
+		if state.Label != "" && !store.HasLabel(moment, state.Label) {
+			return false
+		}
+		if state.Month != "" && time.Unix(moment.CreatedAt, 0).Format("2006-01") != state.Month {
+			return false
+		}
+		return true
+	})
+
+	return store.Paginate(moments, state.Page, browsePageSize), nil
+}
+
+// fetchGitHubPage 从 GitHub 获取一页动态，页码和总页数来自 Link 响应头。
+// issues 接口不支持按创建时间筛选，按月份筛选时改用搜索接口
+func fetchGitHubPage(state config.BrowseState) (*store.Page, error) {
+	var result *github.IssuePage
+	var err error
+	if state.Month != "" {
+		start, parseErr := time.Parse("2006-01", state.Month)
+		if parseErr != nil {
+			return nil, fmt.Errorf("无效的月份: %s", state.Month)
+		}
+		result, err = github.SearchMoments(github.SearchOptions{
+			Label:   state.Label,
+			After:   start.Format("2006-01-02"),
+			Before:  start.AddDate(0, 1, 0).Format("2006-01-02"),
+			Page:    state.Page,
+			PerPage: browsePageSize,
+		})
+	} else {
+		result, err = github.ListIssues(github.ListIssuesOptions{
+			State:   "open",
+			Label:   state.Label,
+			Page:    state.Page,
+			PerPage: browsePageSize,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return store.PageFromIssues(result), nil
+}
+
+// buildBrowserText 生成动态浏览器的消息文本
+func buildBrowserText(state config.BrowseState, page *store.Page) string {
+	var message string
+	if state.Search {
+		message = fmt.Sprintf("🔍 搜索结果（第 %d/%d 页）\n", page.Page, page.LastPage)
+		message += describeSearch(state)
+	} else {
+		message = fmt.Sprintf("📋 动态列表（第 %d/%d 页）\n", page.Page, page.LastPage)
+		message += describeBrowseFilter(state)
+	}
+	message += "\n"
+
+	if len(page.Moments) == 0 {
+		if state.Search {
+			message += "📝 没有找到匹配的动态"
+		} else {
+			message += "📝 暂无动态"
+		}
+		return message
+	}
+
+	for _, moment := range page.Moments {
+		message += fmt.Sprintf("#%d · %s\n%s\n\n", moment.IssueNumber, formatUnixTime(moment.CreatedAt), truncateText(moment.Content, 50))
+	}
+
+	message += "💡 点击下方按钮查看、编辑或删除动态"
+	return message
+}
+
+// describeBrowseFilter 描述当前筛选条件
+func describeBrowseFilter(state config.BrowseState) string {
+	label := "全部"
+	if state.Label != "" {
+		label = state.Label
+	}
+	month := "全部"
+	if state.Month != "" {
+		month = state.Month
+	}
+	return fmt.Sprintf("🏷️ 标签：%s　📅 月份：%s\n", label, month)
+}
+
+// buildBrowserKeyboard 生成动态浏览器的键盘：每条动态一行操作按钮，加上翻页和筛选按钮
+func buildBrowserKeyboard(state config.BrowseState, page *store.Page) tgbotapi.InlineKeyboardMarkup {
+	var buttons [][]tgbotapi.InlineKeyboardButton
+
+	for _, moment := range page.Moments {
+		// 搜索结果直接链接到 Issue，浏览模式则在对话中查看详情
+		viewButton := tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("👁 #%d", moment.IssueNumber), fmt.Sprintf("browse:view:%d", moment.IssueNumber))
+		if state.Search && moment.HTMLURL != "" {
+			viewButton = tgbotapi.NewInlineKeyboardButtonURL(fmt.Sprintf("🔗 #%d", moment.IssueNumber), moment.HTMLURL)
+		}
+		row := []tgbotapi.InlineKeyboardButton{
+			viewButton,
+			tgbotapi.NewInlineKeyboardButtonData("✏️ 编辑", fmt.Sprintf("browse:edit:%d", moment.IssueNumber)),
+			tgbotapi.NewInlineKeyboardButtonData("🗑️ 删除", fmt.Sprintf("browse:delete:%d", moment.IssueNumber)),
+		}
+		buttons = append(buttons, row)
+	}
+
+	var navRow []tgbotapi.InlineKeyboardButton
+	if page.HasPrev() {
+		navRow = append(navRow, tgbotapi.NewInlineKeyboardButtonData("⬅️ 上一页", fmt.Sprintf("browse:page:%d", page.Page-1)))
+	}
+	if page.HasNext() {
+		navRow = append(navRow, tgbotapi.NewInlineKeyboardButtonData("下一页 ➡️", fmt.Sprintf("browse:page:%d", page.Page+1)))
+	}
+	if len(navRow) > 0 {
+		buttons = append(buttons, navRow)
+	}
+
+	filterRow := []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("🏷️ 按标签", "browse:labels"),
+	}
+	if state.Search {
+		// 搜索模式下用 after/before 限定时间，不提供月份筛选
+		filterRow = append(filterRow, tgbotapi.NewInlineKeyboardButtonData("🧹 退出搜索", "browse:clear"))
+	} else {
+		filterRow = append(filterRow, tgbotapi.NewInlineKeyboardButtonData("📅 按月份", "browse:months"))
+		if state.Label != "" || state.Month != "" {
+			filterRow = append(filterRow, tgbotapi.NewInlineKeyboardButtonData("🧹 清除筛选", "browse:clear"))
+		}
+	}
+	buttons = append(buttons, filterRow)
+
+	return tgbotapi.NewInlineKeyboardMarkup(buttons...)
+}
+
+// buildBrowseLabelKeyboard 生成标签筛选键盘（使用标签序号，避免超出回调数据长度限制）
+func buildBrowseLabelKeyboard() tgbotapi.InlineKeyboardMarkup {
+	var buttons [][]tgbotapi.InlineKeyboardButton
+	labels := config.GetLabels()
+
+	// 每行3个按钮
+	for i := 0; i < len(labels); i += 3 {
+		var row []tgbotapi.InlineKeyboardButton
+		for j := 0; j < 3 && i+j < len(labels); j++ {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(labels[i+j], fmt.Sprintf("browse:label:%d", i+j)))
+		}
+		buttons = append(buttons, row)
+	}
+
+	buttons = append(buttons, []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("全部标签", "browse:label:-1"),
+		tgbotapi.NewInlineKeyboardButtonData("↩️ 返回", "browse:back"),
+	})
+
+	return tgbotapi.NewInlineKeyboardMarkup(buttons...)
+}
+
+// buildBrowseMonthKeyboard 生成月份筛选键盘（最近 12 个月）
+func buildBrowseMonthKeyboard() tgbotapi.InlineKeyboardMarkup {
+	var buttons [][]tgbotapi.InlineKeyboardButton
+	now := time.Now()
+	current := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+	var row []tgbotapi.InlineKeyboardButton
+	for i := 0; i < browseMonthSpan; i++ {
+		month := current.AddDate(0, -i, 0).Format("2006-01")
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(month, "browse:month:"+month))
+		if len(row) == 3 {
+			buttons = append(buttons, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		buttons = append(buttons, row)
+	}
+
+	buttons = append(buttons, []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("全部月份", "browse:month:all"),
+		tgbotapi.NewInlineKeyboardButtonData("↩️ 返回", "browse:back"),
+	})
+
+	return tgbotapi.NewInlineKeyboardMarkup(buttons...)
+}
+
+// handleBrowseCallback 处理动态浏览器的回调
+func handleBrowseCallback(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery) error {
+	answerCallback(bot, callback, "")
+
+	chatID := callback.From.ID
+	messageID := callback.Message.MessageID
+	action := strings.TrimPrefix(callback.Data, "browse:")
+	state := config.GetBrowseState(chatID)
+
+	switch {
+	case strings.HasPrefix(action, "page:"):
+		page, err := strconv.Atoi(strings.TrimPrefix(action, "page:"))
+		if err != nil {
+			return nil
+		}
+		state.Page = page
+		config.SetBrowseState(chatID, state)
+		return showMomentBrowser(bot, chatID, messageID)
+
+	case action == "labels":
+		keyboard := buildBrowseLabelKeyboard()
+		return sendOrEditMessage(bot, chatID, messageID, "🏷️ 选择要筛选的标签：", &keyboard)
+
+	case strings.HasPrefix(action, "label:"):
+		index, err := strconv.Atoi(strings.TrimPrefix(action, "label:"))
+		if err != nil {
+			return nil
+		}
+		labels := config.GetLabels()
+		state.Label = ""
+		if index >= 0 && index < len(labels) {
+			state.Label = labels[index]
+		}
+		state.Page = 1
+		config.SetBrowseState(chatID, state)
+		return showMomentBrowser(bot, chatID, messageID)
+
+	case action == "months":
+		keyboard := buildBrowseMonthKeyboard()
+		return sendOrEditMessage(bot, chatID, messageID, "📅 选择要筛选的月份：", &keyboard)
+
+	case strings.HasPrefix(action, "month:"):
+		month := strings.TrimPrefix(action, "month:")
+		if month == "all" {
+			month = ""
+		}
+		state.Month = month
+		state.Page = 1
+		config.SetBrowseState(chatID, state)
+		return showMomentBrowser(bot, chatID, messageID)
+
+	case action == "clear":
+		config.SetBrowseState(chatID, config.BrowseState{Page: 1})
+		return showMomentBrowser(bot, chatID, messageID)
+
+	case action == "back":
+		return showMomentBrowser(bot, chatID, messageID)
+
+	case strings.HasPrefix(action, "view:"):
+		issueNumber, err := strconv.Atoi(strings.TrimPrefix(action, "view:"))
+		if err != nil {
+			return nil
+		}
+		return showMoment(bot, chatID, issueNumber)
+
+	case strings.HasPrefix(action, "edit:"):
+		issueNumber, err := strconv.Atoi(strings.TrimPrefix(action, "edit:"))
+		if err != nil {
+			return nil
+		}
+		return startEdit(bot, chatID, issueNumber)
+
+	case strings.HasPrefix(action, "delete:"):
+		issueNumber, err := strconv.Atoi(strings.TrimPrefix(action, "delete:"))
+		if err != nil {
+			return nil
+		}
+		return confirmDelete(bot, chatID, issueNumber)
+	}
+
+	return nil
+}
+
+// showMoment 发送单条动态的详情和操作按钮
+func showMoment(bot *tgbotapi.BotAPI, chatID int64, issueNumber int) error {
+	moment, err := store.GetOrFetch(issueNumber)
+	if err != nil {
+		return safeSendMessage(bot, chatID, fmt.Sprintf("❌ 无法获取动态 #%d\n\n错误：%v", issueNumber, err))
+	}
+
+	message := formatMomentDetail(moment)
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonURL("🔗 查看", moment.HTMLURL),
+			tgbotapi.NewInlineKeyboardButtonData("✏️ 编辑", fmt.Sprintf("browse:edit:%d", moment.IssueNumber)),
+			tgbotapi.NewInlineKeyboardButtonData("🗑️ 删除", fmt.Sprintf("browse:delete:%d", moment.IssueNumber)),
+		),
+	)
+
+	msg := tgbotapi.NewMessage(chatID, cleanUTF8String(message))
+	msg.ReplyMarkup = keyboard
+	_, err = bot.Send(msg)
+	return err
+}
+
+// formatMomentDetail 生成单条动态的详情文本
+func formatMomentDetail(moment *types.PublishedMoment) string {
+	message := fmt.Sprintf("📄 动态 #%d · %s\n\n", moment.IssueNumber, formatUnixTime(moment.CreatedAt))
+	message += moment.Content + "\n\n"
+	if len(moment.Labels) > 0 {
+		message += "🏷️ 标签：" + strings.Join(moment.Labels, ", ")
+	}
+	return message
+}
+
+// formatUnixTime 将索引中的时间戳格式化为日期
+func formatUnixTime(value int64) string {
+	if value == 0 {
+		return "-"
+	}
+	return time.Unix(value, 0).Format("2006-01-02 15:04")
+}
+
+// formatIssueDate 将 GitHub 返回的时间格式化为日期
+func formatIssueDate(value string) string {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return value
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
@@ -7,7 +7,7 @@ import (
 	"strings"
 	"moments-go/config"
 	"moments-go/github"
-	"moments-go/telegram"
+	"moments-go/store"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
@@ -29,6 +29,26 @@ func HandleCallbackQuery(bot *tgbotapi.BotAPI, update tgbotapi.Update) error {
 		return handleDeleteCallback(bot, callback)
 	}
 	
+	// 处理动态浏览器回调
+	if strings.HasPrefix(data, "browse:") {
+		return handleBrowseCallback(bot, callback)
+	}
+	
+	// 处理回收站回调
+	if strings.HasPrefix(data, "trash:") {
+		return handleTrashCallback(bot, callback)
+	}
+	
+	// 处理撤回回调
+	if strings.HasPrefix(data, "undo:") {
+		return handleUndoCallback(bot, callback)
+	}
+	
+	// 处理清理未引用文件回调
+	if strings.HasPrefix(data, "gc:") {
+		return handleGCCallback(bot, callback)
+	}
+	
 	return nil
 }
 
@@ -47,6 +67,10 @@ func handleLabelCallback(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery)
 		return nil
 	}
 	
+	if label == "watermark" {
+		return handleWatermarkToggle(bot, callback)
+	}
+	
 	if label == "refresh" {
 		// 刷新标签
 		labels, err := github.GetGitHubLabels()
@@ -61,7 +85,10 @@ func handleLabelCallback(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery)
 		config.SetLabels(labels)
 		
 		// 重新创建键盘
-		newKeyboard := createLabelKeyboard()
+		config.MediaMutex.RLock()
+		pending := config.PendingMedia[callback.From.ID]
+		config.MediaMutex.RUnlock()
+		newKeyboard := createMediaLabelKeyboard(pending)
 		message := "🔄 标签已刷新！\n\n💡 请选择标签，然后可以发送文字来更新动态内容！"
 		
 		msg := tgbotapi.NewEditMessageTextAndMarkup(callback.From.ID, callback.Message.MessageID, message, newKeyboard)
@@ -118,17 +145,17 @@ func handleLabelCallback(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery)
 	}
 	
 	// 为媒体文件设置定时器，5分钟后自动发布
-	telegram.ScheduleMediaPublish(bot, callback.From.ID, func() {
-		config.MediaMutex.RLock()
-		_, exists := config.PendingMedia[callback.From.ID]
-		config.MediaMutex.RUnlock()
-		if exists {
-			ProcessPendingMedia(bot, callback.From.ID, "")
-		}
-	})
+	schedulePendingPublish(bot, callback.From.ID)
 	
 	// 更新消息
 	message := fmt.Sprintf("✅ 已选择标签：%s\n\n💡 你可以继续发送文字来更新动态内容，或者等待5分钟后自动发布。", label)
+	if watermarkApplicable(pending) {
+		if watermarkEnabled(pending, pending.Labels) {
+			message += "\n\n💧 将添加水印"
+		} else {
+			message += "\n\n💧 不添加水印"
+		}
+	}
 	msg := tgbotapi.NewEditMessageText(callback.From.ID, callback.Message.MessageID, message)
 	bot.Send(msg)
 	
@@ -169,13 +196,11 @@ func handleDeleteCallback(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery
 			return nil
 		}
 		
-		// 从缓存中删除
-		config.PublishedMutex.Lock()
-		delete(config.PublishedMoments, issueNumber)
-		config.PublishedMutex.Unlock()
+		// 更新本地索引中的状态
+		store.SetState(issueNumber, "closed")
 		
 		// 更新消息显示删除成功
-		successMsg := tgbotapi.NewEditMessageText(callback.From.ID, callback.Message.MessageID, fmt.Sprintf("✅ 动态 #%d 已删除", issueNumber))
+		successMsg := tgbotapi.NewEditMessageText(callback.From.ID, callback.Message.MessageID, fmt.Sprintf("✅ 动态 #%d 已移入回收站\n\n♻️ 发送 /trash 可以恢复", issueNumber))
 		bot.Send(successMsg)
 		
 		return nil
@@ -13,20 +13,29 @@ func HandleStartCommand(bot *tgbotapi.BotAPI, update tgbotapi.Update) error {
 	message := `你好！欢迎使用机器人。
 
 使用方法：
-1. 发送图片/视频，会自动弹出标签选择按钮
+1. 发送图片/视频/文件/语音/音频/动图/贴纸，会自动弹出标签选择按钮
+   发送位置或地点生成打卡动态，紧跟在图片后发送时附加到该图片
 2. 发送文字消息，也会弹出标签选择按钮
 3. 发送 /tags 查看所有可用标签
 4. 发送 /refresh 刷新标签列表
-5. 发送 /edit <编号> 编辑指定动态
-6. 发送 /delete <编号> 删除指定动态
-7. 发送 /cancel 取消编辑
+5. 发送 /list 浏览所有动态（支持翻页、按标签和月份筛选）
+6. 发送 /search <关键词> 搜索动态（支持 label:、after:、before:）
+7. 发送 /edit <编号> 编辑指定动态
+8. 发送 /delete <编号> 删除指定动态
+9. 发送 /trash 查看回收站，恢复已删除的动态
+10. 发送 /stats 查看动态统计
+11. 发送 /sync 同步本地动态索引（/sync full 全量重建）
+12. 发送 /gc 清理文件仓库中未被引用的文件
+13. 发送 /cancel 取消编辑
 
 💡 提示：
 • 发送媒体文件或文字后，选择标签即可发布动态
 • 选择标签后，可以继续发送文字来更新动态内容
 • 媒体文件会在5分钟后自动发布（如果未手动发布）
+• 发布成功后可以在时间窗口内点击「↩️ 撤回」，内容会恢复为草稿
 • 发布后可以使用 /edit 命令编辑动态内容
-• 可以使用 /delete 命令删除不需要的动态`
+• 可以使用 /delete 命令删除不需要的动态
+• /edit 和 /delete 不带编号时会打开动态浏览器`
 	return safeSendMessage(bot, update.Message.Chat.ID, message)
 }
 
@@ -38,20 +47,29 @@ func HandleUnknownCommand(bot *tgbotapi.BotAPI, update tgbotapi.Update) error {
 	message := `❓ 未知命令
 
 使用方法：
-1. 发送图片/视频，会自动弹出标签选择按钮
+1. 发送图片/视频/文件/语音/音频/动图/贴纸，会自动弹出标签选择按钮
+   发送位置或地点生成打卡动态，紧跟在图片后发送时附加到该图片
 2. 发送文字消息，也会弹出标签选择按钮
 3. 发送 /tags 查看所有可用标签
 4. 发送 /refresh 刷新标签列表
-5. 发送 /edit <编号> 编辑指定动态
-6. 发送 /delete <编号> 删除指定动态
-7. 发送 /cancel 取消编辑
+5. 发送 /list 浏览所有动态（支持翻页、按标签和月份筛选）
+6. 发送 /search <关键词> 搜索动态（支持 label:、after:、before:）
+7. 发送 /edit <编号> 编辑指定动态
+8. 发送 /delete <编号> 删除指定动态
+9. 发送 /trash 查看回收站，恢复已删除的动态
+10. 发送 /stats 查看动态统计
+11. 发送 /sync 同步本地动态索引（/sync full 全量重建）
+12. 发送 /gc 清理文件仓库中未被引用的文件
+13. 发送 /cancel 取消编辑
 
 💡 提示：
 • 发送媒体文件或文字后，选择标签即可发布动态
 • 选择标签后，可以继续发送文字来更新动态内容
 • 媒体文件会在5分钟后自动发布（如果未手动发布）
+• 发布成功后可以在时间窗口内点击「↩️ 撤回」，内容会恢复为草稿
 • 发布后可以使用 /edit 命令编辑动态内容
-• 可以使用 /delete 命令删除不需要的动态`
+• 可以使用 /delete 命令删除不需要的动态
+• /edit 和 /delete 不带编号时会打开动态浏览器`
 	return safeSendMessage(bot, update.Message.Chat.ID, message)
 }
 
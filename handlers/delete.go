@@ -4,10 +4,8 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
-	"time"
 	"moments-go/config"
-	"moments-go/github"
-	"moments-go/types"
+	"moments-go/store"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
@@ -24,36 +22,26 @@ func HandleDeleteCommand(bot *tgbotapi.BotAPI, update tgbotapi.Update) error {
 	
 	parts := strings.Fields(text)
 	if len(parts) < 2 {
-		// 显示最近的动态列表供选择
-		return showRecentMomentsForDelete(bot, update.Message.Chat.ID)
+		// 打开动态浏览器供选择
+		return openMomentBrowser(bot, update.Message.Chat.ID)
 	}
 	
 	// 解析 Issue Number
 	issueNumberStr := parts[1]
 	issueNumber, err := strconv.Atoi(issueNumberStr)
 	if err != nil {
-		return safeSendMessage(bot, update.Message.Chat.ID, "❌ 无效的动态编号\n\n💡 发送 /delete 查看最近的动态列表")
+		return safeSendMessage(bot, update.Message.Chat.ID, "❌ 无效的动态编号\n\n💡 发送 /delete 浏览动态列表")
 	}
 	
-	// 获取动态内容
-	moment, exists := config.GetPublishedMoment(issueNumber)
-	if !exists {
-		// 尝试从 GitHub 获取
-		issue, err := github.GetGitHubIssue(issueNumber)
-		if err != nil {
-			return safeSendMessage(bot, update.Message.Chat.ID, fmt.Sprintf("❌ 无法获取动态 #%d\n\n错误：%v", issueNumber, err))
-		}
-		
-		// 创建动态对象并缓存
-		moment = &types.PublishedMoment{
-			IssueID:     issue.ID,
-			IssueNumber: issue.Number,
-			Content:     issue.Body,
-			Labels:      []string{},
-			CreatedAt:   time.Now().Unix(),
-			UpdatedAt:   time.Now().Unix(),
-		}
-		config.AddPublishedMoment(moment)
+	return confirmDelete(bot, update.Message.Chat.ID, issueNumber)
+}
+
+// confirmDelete 发送删除指定动态的确认消息
+func confirmDelete(bot *tgbotapi.BotAPI, chatID int64, issueNumber int) error {
+	// 获取动态内容（优先使用本地索引）
+	moment, err := store.GetOrFetch(issueNumber)
+	if err != nil {
+		return safeSendMessage(bot, chatID, fmt.Sprintf("❌ 无法获取动态 #%d\n\n错误：%v", issueNumber, err))
 	}
 	
 	// 创建确认删除的键盘
@@ -87,38 +75,14 @@ func HandleDeleteCommand(bot *tgbotapi.BotAPI, update tgbotapi.Update) error {
 		message += "\n\n"
 	}
 	
-	message += "⚠️ 删除后无法恢复，请确认！"
+	message += "⚠️ 删除后动态会移入回收站，可通过 /trash 恢复"
+	if config.Cfg.TrashRetentionDays > 0 {
+		message += fmt.Sprintf("，%d 天后永久删除", config.Cfg.TrashRetentionDays)
+	}
+	message += "，请确认！"
 	
-	msg := tgbotapi.NewMessage(update.Message.Chat.ID, cleanUTF8String(message))
+	msg := tgbotapi.NewMessage(chatID, cleanUTF8String(message))
 	msg.ReplyMarkup = keyboard
 	_, sendErr := bot.Send(msg)
 	return sendErr
 }
-
-// showRecentMomentsForDelete 显示最近的动态列表（用于删除）
-func showRecentMomentsForDelete(bot *tgbotapi.BotAPI, chatID int64) error {
-	issues, err := github.GetRecentIssues(10)
-	if err != nil {
-		return safeSendMessage(bot, chatID, fmt.Sprintf("❌ 获取动态列表失败：%v", err))
-	}
-	
-	if len(issues) == 0 {
-		return safeSendMessage(bot, chatID, "📝 暂无动态")
-	}
-	
-	message := "🗑️ 选择要删除的动态：\n\n"
-	for _, issue := range issues {
-		// 截取内容预览
-		preview := issue.Body
-		if len(preview) > 50 {
-			preview = preview[:50] + "..."
-		}
-		
-		message += fmt.Sprintf("#%d - %s\n", issue.Number, preview)
-	}
-	
-	message += "\n💡 发送 /delete <编号> 删除指定动态\n"
-	message += "例如：/delete 123"
-	
-	return safeSendMessage(bot, chatID, message)
-} 
@@ -0,0 +1,356 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"moments-go/config"
+	"moments-go/media"
+	"moments-go/telegram"
+	"moments-go/types"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// commonExtensions 常见 MIME 类型对应的扩展名（mime 包返回的扩展名不够直观，如 .jpe）
+var commonExtensions = map[string]string{
+	"image/jpeg":       "jpg",
+	"image/png":        "png",
+	"image/gif":        "gif",
+	"image/webp":       "webp",
+	"image/bmp":        "bmp",
+	"video/mp4":        "mp4",
+	"video/webm":       "webm",
+	"video/quicktime":  "mov",
+	"audio/mpeg":       "mp3",
+	"audio/ogg":        "ogg",
+	"audio/wave":       "wav",
+	"audio/mp4":        "m4a",
+	"application/pdf":  "pdf",
+	"application/zip":  "zip",
+	"application/gzip": "gz",
+	"text/plain":       "txt",
+}
+
+// HandleDocumentMessage 处理文件消息（以文件形式发送的原图、PDF、压缩包等）
+func HandleDocumentMessage(bot *tgbotapi.BotAPI, update tgbotapi.Update) error {
+	if !config.IsAuthorizedUser(update.Message.Chat.ID) {
+		return nil
+	}
+	document := update.Message.Document
+	if document == nil {
+		return nil
+	}
+	limit := fileSizeLimit()
+	if strings.HasPrefix(document.MimeType, "video/") {
+		limit = videoSizeLimit()
+	}
+	if int64(document.FileSize) > limit {
+		return safeSendMessage(bot, update.Message.Chat.ID, fmt.Sprintf("❌ 文件过大，请上传小于 %s 的文件", formatSize(limit)))
+	}
+
+	pending := &types.PendingMedia{
+		FileID:       document.FileID,
+		FileUniqueID: document.FileUniqueID,
+		Type:         "document",
+		Caption:      update.Message.Caption,
+		Labels:       []string{},
+		FileName:     document.FileName,
+		FileSize:     int64(document.FileSize),
+		MimeType:     document.MimeType,
+	}
+
+	title := "📎 文件已接收！"
+	if document.FileName != "" {
+		title += fmt.Sprintf("\n\n文件名：%s", document.FileName)
+	}
+	return receivePendingMedia(bot, update, pending, title)
+}
+
+// receivePendingMedia 保存待发布的媒体，设置自动发布定时器并弹出标签选择键盘。
+// 相册中的媒体逐条到达，之后收到的加入第一条所在的待发布动态，不再弹出键盘
+func receivePendingMedia(bot *tgbotapi.BotAPI, update tgbotapi.Update, pending *types.PendingMedia, title string) error {
+	chatID := update.Message.Chat.ID
+
+	pending.Source = messageSource(update.Message)
+	pending.MediaGroupID = update.Message.MediaGroupID
+
+	config.MediaMutex.Lock()
+	if current, exists := config.PendingMedia[chatID]; exists && pending.MediaGroupID != "" && current.MediaGroupID == pending.MediaGroupID {
+		current.Group = append(current.Group, pending)
+		if current.Caption == "" {
+			// 相册的文字可能附在任意一条媒体上
+			current.Caption = pending.Caption
+		}
+		config.MediaMutex.Unlock()
+		return nil
+	}
+	config.PendingMedia[chatID] = pending
+	config.MediaMutex.Unlock()
+
+	// 设置定时器，5分钟后自动发布
+	schedulePendingPublish(bot, chatID)
+
+	// 创建标签选择键盘
+	keyboard := createMediaLabelKeyboard(pending)
+	message := title
+	if update.Message.Caption != "" {
+		message += fmt.Sprintf("\n\n当前文字：%s", update.Message.Caption)
+	}
+	message += "\n\n💡 请选择标签，然后可以发送文字来更新动态内容！"
+
+	msg := tgbotapi.NewMessage(chatID, cleanUTF8String(message))
+	msg.ReplyMarkup = keyboard
+	_, err := bot.Send(msg)
+	return err
+}
+
+// buildMediaFile 根据下载的内容识别真实类型并生成上传文件：图片需要在内存中处理，读入内存；
+// 其他文件上传时从暂存文件以流的形式读取
+func buildMediaFile(pending *types.PendingMedia, spooled *telegram.SpooledFile, timestamp int64) (*types.MediaFile, error) {
+	head, err := spooled.Head()
+	if err != nil {
+		return nil, err
+	}
+
+	releaseAsset := uploadAsReleaseAsset(pending)
+	declared := pending.MimeType
+	if releaseAsset && declared == "" && pending.Type == "video" {
+		declared = "video/mp4"
+	}
+	file := newMediaFile(pending, detectMimeType(head, declared), timestamp)
+
+	if strings.HasPrefix(file.Type, "image/") && !releaseAsset {
+		content, err := spooled.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("读取暂存文件失败: %v", err)
+		}
+		file.Content = content
+		return file, nil
+	}
+
+	file.Open = spooled.Open
+	file.Size = spooled.Size
+	file.ReleaseAsset = releaseAsset
+	return file, nil
+}
+
+// newMediaFile 根据识别出的类型生成文件名和上传文件，不包含文件内容
+func newMediaFile(pending *types.PendingMedia, mimeType string, timestamp int64) *types.MediaFile {
+	extension := extensionForMimeType(mimeType, pending.FileName)
+
+	var name string
+	if pending.FileName != "" {
+		// 保留原始文件名，缺少扩展名时补上识别出的扩展名
+		name = sanitizeFileName(pending.FileName)
+		if filepath.Ext(name) == "" && extension != "" {
+			name += "." + extension
+		}
+	} else {
+		name = fmt.Sprintf("%s_%d", pending.Type, timestamp)
+		if extension != "" {
+			name += "." + extension
+		}
+	}
+
+	return &types.MediaFile{
+		Name:      name,
+		Type:      mimeType,
+		Duration:  pending.Duration,
+		Performer: pending.Performer,
+		Title:     pending.Title,
+		Loop:      pending.Type == "animation",
+		Width:     pending.Width,
+		Height:    pending.Height,
+	}
+}
+
+// attachThumbnail 下载封面缩略图并附加到媒体文件，失败时不影响发布
+func attachThumbnail(bot *tgbotapi.BotAPI, pending *types.PendingMedia, file *types.MediaFile) {
+	if pending.ThumbFileID == "" {
+		return
+	}
+
+	content, err := telegram.DownloadFile(bot, pending.ThumbFileID)
+	if err != nil {
+		log.Printf("下载封面缩略图失败: %v", err)
+		return
+	}
+
+	mimeType := detectMimeType(content, "image/jpeg")
+	if mimeType == "image/webp" {
+		// 贴纸缩略图为 WebP，转换为兼容性更好的 PNG
+		if converted, err := media.WebPToPNG(content); err == nil {
+			content = converted
+			mimeType = "image/png"
+		}
+	}
+	base := strings.TrimSuffix(file.Name, filepath.Ext(file.Name))
+	file.Thumbnail = &types.MediaFile{
+		Name:    fmt.Sprintf("%s_cover.%s", base, extensionForMimeType(mimeType, "")),
+		Content: content,
+		Type:    mimeType,
+	}
+}
+
+// detectMimeType 通过文件内容识别 MIME 类型，无法识别时使用 Telegram 提供的类型
+func detectMimeType(content []byte, declared string) string {
+	detected := http.DetectContentType(content)
+	if index := strings.Index(detected, ";"); index >= 0 {
+		detected = detected[:index]
+	}
+
+	switch detected {
+	case "application/octet-stream", "text/plain":
+		if declared != "" {
+			return declared
+		}
+	case "application/ogg":
+		// 语音消息和 OGG 音频都会被识别为 application/ogg
+		return "audio/ogg"
+	}
+
+	return detected
+}
+
+// extensionForMimeType 获取 MIME 类型对应的扩展名，未知类型时沿用原文件名的扩展名
+func extensionForMimeType(mimeType, fileName string) string {
+	if extension, exists := commonExtensions[mimeType]; exists {
+		return extension
+	}
+	if extension := strings.TrimPrefix(filepath.Ext(fileName), "."); extension != "" {
+		return strings.ToLower(extension)
+	}
+	if extensions, err := mime.ExtensionsByType(mimeType); err == nil && len(extensions) > 0 {
+		return strings.TrimPrefix(extensions[0], ".")
+	}
+	return "bin"
+}
+
+// sanitizeFileName 清理文件名中的路径分隔符、空白和控制字符
+func sanitizeFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	cleaned := strings.Map(func(r rune) rune {
+		switch {
+		case r < 0x20 || r == 0x7f:
+			return -1
+		case r == ' ' || r == '/' || r == '#' || r == '?' || r == '%':
+			return '_'
+		}
+		return r
+	}, cleanUTF8String(name))
+
+	if cleaned == "" || cleaned == "." || cleaned == ".." {
+		return "file"
+	}
+	return cleaned
+}
+
+// defaultMediaContent 未填写文字时的默认动态内容
+func defaultMediaContent(pending *types.PendingMedia) string {
+	if len(pending.Group) > 0 {
+		return defaultAlbumContent(pending)
+	}
+
+	switch pending.Type {
+	case "photo":
+		return "📷 分享了一张图片"
+	case "video":
+		return "🎥 分享了一个视频"
+	case "voice":
+		return "🎙️ 分享了一段语音"
+	case "audio":
+		if description := describeAudio(pending); description != "" {
+			return fmt.Sprintf("🎵 分享了一首音乐：%s", description)
+		}
+		return "🎵 分享了一段音频"
+	case "animation":
+		return "🎞️ 分享了一个动图"
+	case "sticker":
+		if pending.Emoji != "" {
+			return "🧩 分享了一个贴纸 " + pending.Emoji
+		}
+		return "🧩 分享了一个贴纸"
+	case "document":
+		if pending.FileName != "" {
+			return fmt.Sprintf("📎 分享了一个文件：%s", pending.FileName)
+		}
+		return "📎 分享了一个文件"
+	}
+	return "📎 分享了一个文件"
+}
+
+// defaultAlbumContent 相册未填写文字时的默认动态内容，媒体类型相同时按类型描述
+func defaultAlbumContent(pending *types.PendingMedia) string {
+	count := len(pending.Group) + 1
+	for _, item := range pending.Group {
+		if item.Type != pending.Type {
+			return fmt.Sprintf("🖼️ 分享了 %d 个媒体文件", count)
+		}
+	}
+
+	switch pending.Type {
+	case "photo":
+		return fmt.Sprintf("📷 分享了 %d 张图片", count)
+	case "video":
+		return fmt.Sprintf("🎥 分享了 %d 个视频", count)
+	case "audio":
+		return fmt.Sprintf("🎵 分享了 %d 段音频", count)
+	}
+	return fmt.Sprintf("📎 分享了 %d 个文件", count)
+}
+
+// albumFileName 相册中第 index 个（从 0 开始）媒体的文件名追加序号，避免同一条动态中按时间戳生成的文件名重复
+func albumFileName(name string, index int) string {
+	if index == 0 {
+		return name
+	}
+	extension := filepath.Ext(name)
+	return fmt.Sprintf("%s_%d%s", strings.TrimSuffix(name, extension), index+1, extension)
+}
+
+// altText 根据动态内容生成图片的替代文本：取第一行，去掉 Markdown 中有特殊含义的字符
+func altText(content string) string {
+	line := strings.TrimSpace(strings.SplitN(content, "\n", 2)[0])
+	line = strings.NewReplacer("[", "", "]", "", "(", "", ")", "", "!", "").Replace(line)
+	return truncateText(line, 50)
+}
+
+// fileSizeLimit 文件大小上限：本地模式的 Bot API 服务器为 2GB，否则为 50MB，且不超过 Bot API 允许下载的大小
+func fileSizeLimit() int64 {
+	if config.Cfg.TelegramLocalMode {
+		return config.MaxDownloadSize()
+	}
+	return min(config.MaxFileSize, config.MaxDownloadSize())
+}
+
+// videoSizeLimit 视频大小上限：启用 Release 附件时为 2GB，否则为 50MB，且不超过 Bot API 允许下载的大小
+func videoSizeLimit() int64 {
+	if config.Cfg.ReleaseAssetThreshold > 0 {
+		return min(config.MaxReleaseAssetSize, config.MaxDownloadSize())
+	}
+	return fileSizeLimit()
+}
+
+// uploadAsReleaseAsset 是否将文件上传为 Release 附件：超过阈值的视频，以及超过 50MB 的其他文件（只在本地模式下出现）
+func uploadAsReleaseAsset(pending *types.PendingMedia) bool {
+	if config.Cfg.ReleaseAssetThreshold <= 0 || pending.FileSize < config.Cfg.ReleaseAssetThreshold {
+		return false
+	}
+	if pending.FileSize > config.MaxFileSize {
+		return true
+	}
+	return pending.Type == "video" || (pending.Type == "document" && strings.HasPrefix(pending.MimeType, "video/"))
+}
+
+// formatSize 将字节数格式化为 MB 或 GB
+func formatSize(size int64) string {
+	if size >= 1024*1024*1024 {
+		return fmt.Sprintf("%.0fGB", float64(size)/1024/1024/1024)
+	}
+	return fmt.Sprintf("%.0fMB", float64(size)/1024/1024)
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"moments-go/config"
+	"moments-go/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// gcReportLimit 报告中最多列出的文件数，避免超过 Telegram 消息长度限制
+const gcReportLimit = 50
+
+var (
+	// 等待确认删除的未引用文件，ChatID -> 文件路径
+	gcCandidates = make(map[int64][]string)
+	gcMutex      sync.Mutex
+)
+
+// HandleGCCommand 处理 /gc 命令，列出文件仓库中未被引用的文件并等待确认删除
+func HandleGCCommand(bot *tgbotapi.BotAPI, update tgbotapi.Update) error {
+	chatID := update.Message.Chat.ID
+	if !config.IsAuthorizedUser(chatID) {
+		return nil
+	}
+
+	if err := safeSendMessage(bot, chatID, "🔍 正在查找未被引用的文件..."); err != nil {
+		return err
+	}
+
+	orphans, err := storage.FindOrphans()
+	if err != nil {
+		return safeSendMessage(bot, chatID, fmt.Sprintf("❌ 查找失败：%v", err))
+	}
+
+	message := "🧹 " + storage.FormatOrphans(orphans, gcReportLimit)
+	if len(orphans) == 0 {
+		return safeSendMessage(bot, chatID, message)
+	}
+
+	var paths []string
+	for _, orphan := range orphans {
+		paths = append(paths, orphan.Path)
+	}
+	gcMutex.Lock()
+	gcCandidates[chatID] = paths
+	gcMutex.Unlock()
+
+	message += "\n⚠️ 确认后会在一次提交中删除以上文件，无法恢复"
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🗑️ 确认删除", "gc:confirm"),
+		tgbotapi.NewInlineKeyboardButtonData("❌ 取消", "gc:cancel"),
+	))
+	return sendOrEditMessage(bot, chatID, 0, message, &keyboard)
+}
+
+// handleGCCallback 处理删除未引用文件的确认回调
+func handleGCCallback(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery) error {
+	chatID := callback.From.ID
+	messageID := callback.Message.MessageID
+
+	gcMutex.Lock()
+	paths, exists := gcCandidates[chatID]
+	delete(gcCandidates, chatID)
+	gcMutex.Unlock()
+
+	if strings.TrimPrefix(callback.Data, "gc:") != "confirm" {
+		return sendOrEditMessage(bot, chatID, messageID, "❌ 已取消清理", nil)
+	}
+	if !exists {
+		return sendOrEditMessage(bot, chatID, messageID, "❌ 清理已过期，请重新发送 /gc", nil)
+	}
+
+	if err := sendOrEditMessage(bot, chatID, messageID, "⏳ 正在删除未被引用的文件...", nil); err != nil {
+		return err
+	}
+
+	deleted, err := storage.DeleteOrphans(paths)
+	if err != nil {
+		return sendOrEditMessage(bot, chatID, messageID, fmt.Sprintf("❌ 删除失败：%v", err), nil)
+	}
+
+	var total int64
+	for _, file := range deleted {
+		total += file.Size
+	}
+	message := fmt.Sprintf("✅ 已删除 %d 个未被引用的文件，释放 %s", len(deleted), storage.FormatBytes(total))
+	if skipped := len(paths) - len(deleted); skipped > 0 {
+		message += fmt.Sprintf("\n\n💡 %d 个文件在确认期间被引用或已删除，已跳过", skipped)
+	}
+	return sendOrEditMessage(bot, chatID, messageID, message, nil)
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"moments-go/config"
+	"moments-go/media"
+	"moments-go/types"
+)
+
+// processImage 上传前处理图片：去除 EXIF（包括 GPS）、缩放、重新编码并生成缩略图。
+// GIF 可能是动画，不做处理；处理失败时保留原图
+func processImage(file *types.MediaFile) {
+	switch file.Type {
+	case "image/jpeg", "image/png", "image/webp":
+	default:
+		return
+	}
+
+	processed, err := media.ProcessImage(file.Content, file.Type, media.ImageOptions{
+		MaxEdge:       config.Cfg.ImageMaxEdge,
+		Quality:       config.Cfg.ImageQuality,
+		ThumbnailEdge: config.Cfg.ImageThumbnailEdge,
+	})
+	if err != nil {
+		log.Printf("处理图片 %s 失败: %v", file.Name, err)
+		return
+	}
+
+	base := strings.TrimSuffix(file.Name, filepath.Ext(file.Name))
+	if processed.Type != file.Type {
+		file.Name = base + "." + extensionForMimeType(processed.Type, "")
+	}
+	file.Content = processed.Content
+	file.Type = processed.Type
+	file.Width = processed.Width
+	file.Height = processed.Height
+
+	if processed.Thumbnail != nil {
+		file.Thumbnail = &types.MediaFile{
+			Name:    fmt.Sprintf("%s_thumb.jpg", base),
+			Content: processed.Thumbnail,
+			Type:    "image/jpeg",
+		}
+	}
+}
+
+// analyzeImage 计算图片的尺寸、主色和 BlurHash，供前端在图片加载前显示占位
+func analyzeImage(file *types.MediaFile) {
+	switch file.Type {
+	case "image/jpeg", "image/png", "image/gif", "image/webp":
+	default:
+		return
+	}
+
+	placeholder, err := media.AnalyzeImage(file.Content, file.Type)
+	if err != nil {
+		log.Printf("分析图片 %s 失败: %v", file.Name, err)
+		return
+	}
+	file.Width = placeholder.Width
+	file.Height = placeholder.Height
+	file.DominantColor = placeholder.DominantColor
+	file.BlurHash = placeholder.BlurHash
+}
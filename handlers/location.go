@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"fmt"
+
+	"moments-go/config"
+	"moments-go/types"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// HandleLocationMessage 处理位置和地点消息，生成打卡动态或附加到待发布的草稿
+func HandleLocationMessage(bot *tgbotapi.BotAPI, update tgbotapi.Update) error {
+	chatID := update.Message.Chat.ID
+	if !config.IsAuthorizedUser(chatID) {
+		return nil
+	}
+
+	var location *types.Location
+	if venue := update.Message.Venue; venue != nil {
+		location = &types.Location{
+			Latitude:  venue.Location.Latitude,
+			Longitude: venue.Location.Longitude,
+			Title:     venue.Title,
+			Address:   venue.Address,
+		}
+	} else if update.Message.Location != nil {
+		location = &types.Location{
+			Latitude:  update.Message.Location.Latitude,
+			Longitude: update.Message.Location.Longitude,
+		}
+	} else {
+		return nil
+	}
+
+	// 已有待发布的内容时，将位置附加到该草稿
+	config.MediaMutex.Lock()
+	pending, exists := config.PendingMedia[chatID]
+	if exists {
+		pending.Location = location
+	}
+	config.MediaMutex.Unlock()
+
+	if exists {
+		message := "📍 位置已附加到待发布的内容！\n\n" + describeLocation(location)
+		message += "\n\n💡 请选择标签来发布动态！"
+		msg := tgbotapi.NewMessage(chatID, cleanUTF8String(message))
+		msg.ReplyMarkup = createMediaLabelKeyboard(pending)
+		_, err := bot.Send(msg)
+		return err
+	}
+
+	config.MediaMutex.Lock()
+	config.PendingMedia[chatID] = &types.PendingMedia{
+		Type:     "location",
+		Labels:   []string{},
+		Location: location,
+		Source:   messageSource(update.Message),
+	}
+	config.MediaMutex.Unlock()
+
+	schedulePendingPublish(bot, chatID)
+
+	message := "📍 打卡位置已接收！\n\n" + describeLocation(location)
+	message += "\n\n💡 请选择标签发布，或发送文字作为打卡内容！"
+	msg := tgbotapi.NewMessage(chatID, cleanUTF8String(message))
+	msg.ReplyMarkup = createLabelKeyboard()
+	_, err := bot.Send(msg)
+	return err
+}
+
+// describeLocation 生成位置的简短说明，用于 Telegram 消息
+func describeLocation(location *types.Location) string {
+	text := ""
+	if location.Title != "" {
+		text += location.Title + "\n"
+	}
+	if location.Address != "" {
+		text += location.Address + "\n"
+	}
+	return text + formatCoordinates(location)
+}
+
+// formatCoordinates 格式化经纬度
+func formatCoordinates(location *types.Location) string {
+	return fmt.Sprintf("%.6f, %.6f", location.Latitude, location.Longitude)
+}
+
+// checkinContent 打卡动态没有文字时使用的默认内容
+func checkinContent(location *types.Location) string {
+	if location.Title != "" {
+		return "📍 在 " + location.Title + " 打卡"
+	}
+	return "📍 打卡"
+}
+
+// publishLabels 发布时使用的标签，带有位置的动态会加上打卡标签
+func publishLabels(pending *types.PendingMedia) []string {
+	labels := append([]string(nil), pending.Labels...)
+	if len(labels) == 0 {
+		labels = []string{"动态"}
+	}
+	if pending.Location == nil || config.Cfg.CheckinLabel == "" {
+		return labels
+	}
+	for _, label := range labels {
+		if label == config.Cfg.CheckinLabel {
+			return labels
+		}
+	}
+	return append(labels, config.Cfg.CheckinLabel)
+}
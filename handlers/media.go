@@ -7,6 +7,9 @@ import (
 	"time"
 	"moments-go/config"
 	"moments-go/github"
+	"moments-go/render"
+	"moments-go/store"
+	"moments-go/storage"
 	"moments-go/telegram"
 	"moments-go/types"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -23,37 +26,16 @@ func HandlePhotoMessage(bot *tgbotapi.BotAPI, update tgbotapi.Update) error {
 	}
 	photo := photos[len(photos)-1]
 	
-	config.MediaMutex.Lock()
-	config.PendingMedia[update.Message.Chat.ID] = &types.PendingMedia{
-		FileID:  photo.FileID,
-		Type:    "photo",
-		Caption: update.Message.Caption,
-		Labels:  []string{},
+	pending := &types.PendingMedia{
+		FileID:       photo.FileID,
+		FileUniqueID: photo.FileUniqueID,
+		Type:         "photo",
+		Caption:      update.Message.Caption,
+		Labels:       []string{},
+		Width:        photo.Width,
+		Height:       photo.Height,
 	}
-	config.MediaMutex.Unlock()
-
-	// 设置定时器，5分钟后自动发布
-	telegram.ScheduleMediaPublish(bot, update.Message.Chat.ID, func() {
-		config.MediaMutex.RLock()
-		_, exists := config.PendingMedia[update.Message.Chat.ID]
-		config.MediaMutex.RUnlock()
-		if exists {
-			ProcessPendingMedia(bot, update.Message.Chat.ID, "")
-		}
-	})
-
-	// 创建标签选择键盘
-	keyboard := createLabelKeyboard()
-	message := "📷 图片已接收！"
-	if update.Message.Caption != "" {
-		message += fmt.Sprintf("\n\n当前文字：%s", update.Message.Caption)
-	}
-	message += "\n\n💡 请选择标签，然后可以发送文字来更新动态内容！"
-	
-	msg := tgbotapi.NewMessage(update.Message.Chat.ID, cleanUTF8String(message))
-	msg.ReplyMarkup = keyboard
-	_, err := bot.Send(msg)
-	return err
+	return receivePendingMedia(bot, update, pending, "📷 图片已接收！")
 }
 
 // HandleVideoMessage 处理视频消息
@@ -65,39 +47,38 @@ func HandleVideoMessage(bot *tgbotapi.BotAPI, update tgbotapi.Update) error {
 	if video == nil {
 		return nil
 	}
-	if video.FileSize > config.MaxFileSize {
-		return safeSendMessage(bot, update.Message.Chat.ID, "❌ 视频文件过大，请上传小于 50MB 的视频")
+	if int64(video.FileSize) > videoSizeLimit() {
+		return safeSendMessage(bot, update.Message.Chat.ID, fmt.Sprintf("❌ 视频文件过大，请上传小于 %s 的视频", formatSize(videoSizeLimit())))
 	}
 	
-	config.MediaMutex.Lock()
-	config.PendingMedia[update.Message.Chat.ID] = &types.PendingMedia{
-		FileID:  video.FileID,
-		Type:    "video",
-		Caption: update.Message.Caption,
-		Labels:  []string{},
+	pending := &types.PendingMedia{
+		FileID:       video.FileID,
+		FileUniqueID: video.FileUniqueID,
+		Type:         "video",
+		Caption:      update.Message.Caption,
+		Labels:       []string{},
+		MimeType:     video.MimeType,
+		FileSize:     int64(video.FileSize),
+		Duration:     video.Duration,
+		Width:        video.Width,
+		Height:       video.Height,
 	}
-	config.MediaMutex.Unlock()
-	telegram.ScheduleMediaPublish(bot, update.Message.Chat.ID, func() {
+	if video.Thumbnail != nil {
+		pending.ThumbFileID = video.Thumbnail.FileID
+	}
+	return receivePendingMedia(bot, update, pending, "🎥 视频已接收！")
+}
+
+// schedulePendingPublish 设置定时器，到时仍未发布的媒体会自动发布
+func schedulePendingPublish(bot *tgbotapi.BotAPI, chatID int64) {
+	telegram.ScheduleMediaPublish(bot, chatID, func() {
 		config.MediaMutex.RLock()
-		_, exists := config.PendingMedia[update.Message.Chat.ID]
+		_, exists := config.PendingMedia[chatID]
 		config.MediaMutex.RUnlock()
 		if exists {
-			ProcessPendingMedia(bot, update.Message.Chat.ID, "")
+			ProcessPendingMedia(bot, chatID, "")
 		}
 	})
-	
-	// 创建标签选择键盘
-	keyboard := createLabelKeyboard()
-	message := "🎥 视频已接收！"
-	if update.Message.Caption != "" {
-		message += fmt.Sprintf("\n\n当前文字：%s", update.Message.Caption)
-	}
-	message += "\n\n💡 请选择标签，然后可以发送文字来更新动态内容！"
-	
-	msg := tgbotapi.NewMessage(update.Message.Chat.ID, cleanUTF8String(message))
-	msg.ReplyMarkup = keyboard
-	_, err := bot.Send(msg)
-	return err
 }
 
 // HandleTextMessage 处理文本消息
@@ -120,10 +101,22 @@ func HandleTextMessage(bot *tgbotapi.BotAPI, update tgbotapi.Update) error {
 			return HandleTagsCommand(bot, update)
 		} else if strings.HasPrefix(text, "/refresh") {
 			return HandleRefreshCommand(bot, update)
+		} else if strings.HasPrefix(text, "/list") {
+			return HandleListCommand(bot, update)
+		} else if strings.HasPrefix(text, "/search") {
+			return HandleSearchCommand(bot, update)
 		} else if strings.HasPrefix(text, "/edit") {
 			return HandleEditCommand(bot, update)
 		} else if strings.HasPrefix(text, "/delete") {
 			return HandleDeleteCommand(bot, update)
+		} else if strings.HasPrefix(text, "/stats") {
+			return HandleStatsCommand(bot, update)
+		} else if strings.HasPrefix(text, "/sync") {
+			return HandleSyncCommand(bot, update)
+		} else if strings.HasPrefix(text, "/trash") {
+			return HandleTrashCommand(bot, update)
+		} else if strings.HasPrefix(text, "/gc") {
+			return HandleGCCommand(bot, update)
 		} else if strings.HasPrefix(text, "/cancel") {
 			return HandleCancelCommand(bot, update)
 		} else {
@@ -152,6 +145,7 @@ func HandleTextMessage(bot *tgbotapi.BotAPI, update tgbotapi.Update) error {
 		Type:    "text",
 		Caption: text,
 		Labels:  []string{},
+		Source:  messageSource(update.Message),
 	}
 	config.MediaMutex.Unlock()
 	
@@ -183,8 +177,8 @@ func ProcessPendingMediaWithProgress(bot *tgbotapi.BotAPI, chatID int64, content
 	delete(config.PendingMedia, chatID)
 	config.MediaMutex.Unlock()
 	
-	// 处理纯文字消息
-	if pending.Type == "text" {
+	// 处理纯文字消息和打卡
+	if pending.FileID == "" {
 		if showProgress {
 			if err := safeSendMessage(bot, chatID, "⏳ 正在发布文字动态..."); err != nil {
 				return err
@@ -195,86 +189,202 @@ func ProcessPendingMediaWithProgress(bot *tgbotapi.BotAPI, chatID int64, content
 		if finalContent == "" {
 			finalContent = pending.Caption
 		}
+		if finalContent == "" && pending.Location != nil {
+			finalContent = checkinContent(pending.Location)
+		}
 		
 		// 使用标签
-		labels := pending.Labels
-		if len(labels) == 0 {
-			labels = []string{"动态"}
-		}
+		labels := publishLabels(pending)
 		
-		issue, err := github.CreateGitHubIssueWithLabels(finalContent, labels)
+		issue, _, err := publishMoment(pending, finalContent, labels, nil, nil)
 		if err != nil {
 			log.Printf("发布文字动态失败: %v", err)
 			return safeSendMessage(bot, chatID, "❌ 发布失败，请稍后重试")
 		}
 		
-		// 缓存已发布的动态信息
-		moment := &types.PublishedMoment{
-			IssueID:     issue.ID,
-			IssueNumber: issue.Number,
-			Content:     finalContent,
-			Labels:      labels,
-			MediaURLs:   []string{},
-			CreatedAt:   time.Now().Unix(),
-			UpdatedAt:   time.Now().Unix(),
+		// 写入本地索引
+		store.PutIssue(issue)
+		
+		// 撤回时恢复的草稿
+		draft := *pending
+		if pending.Type == "text" || content != "" {
+			draft.Caption = finalContent
 		}
-		config.AddPublishedMoment(moment)
 		
 		successMessage := fmt.Sprintf("✅ 文字动态发布成功！\n\n🔗 查看链接：%s", issue.HTMLURL)
-		return safeSendMessage(bot, chatID, successMessage)
+		if pending.Type == "location" {
+			successMessage = fmt.Sprintf("✅ 打卡动态发布成功！\n\n🔗 查看链接：%s", issue.HTMLURL)
+		}
+		return sendPublishSuccess(bot, chatID, successMessage, issue, draft, nil)
 	}
 	
-	// 处理媒体文件
+	// 处理媒体文件，通过编辑同一条消息报告下载、处理和上传的进度
+	var progress *progressMessage
 	if showProgress {
-		if err := safeSendMessage(bot, chatID, "⏳ 正在处理媒体文件..."); err != nil {
+		var err error
+		if progress, err = newProgressMessage(bot, chatID, "⏳ 正在处理媒体文件..."); err != nil {
 			return err
 		}
 	}
-	fileBuffer, err := telegram.DownloadFile(bot, pending.FileID)
-	if err != nil {
-		return err
-	}
 	timestamp := time.Now().Unix()
-	var extension string
-	var fileType string
-	if pending.Type == "photo" {
-		extension = "jpg"
-		fileType = "image/jpeg"
-	} else {
-		extension = "mp4"
-		fileType = "video/mp4"
-	}
-	fileName := fmt.Sprintf("%s_%d.%s", pending.Type, timestamp, extension)
 	finalContent := content
 	if finalContent == "" {
 		finalContent = pending.Caption
 		if finalContent == "" {
-			if pending.Type == "photo" {
-				finalContent = "📷 分享了一张图片"
-			} else {
-				finalContent = "🎥 分享了一个视频"
-			}
+			finalContent = defaultMediaContent(pending)
 		}
 	}
-	mediaFiles := []*types.MediaFile{{
-		Name:    fileName,
-		Content: fileBuffer,
-		Type:    fileType,
-	}}
 	
 	// 使用标签
-	labels := pending.Labels
-	if len(labels) == 0 {
-		labels = []string{"动态"}
+	labels := publishLabels(pending)
+	
+	// 相册中的每个媒体依次下载和处理，之后一起上传
+	items := append([]*types.PendingMedia{pending}, pending.Group...)
+	mediaFiles := make([]*types.MediaFile, 0, len(items))
+	for i, item := range items {
+		// 相册中的媒体使用第一条媒体的水印设置
+		item.Watermark = pending.Watermark
+		prefix := ""
+		if len(items) > 1 {
+			prefix = fmt.Sprintf("（%d/%d）", i+1, len(items))
+		}
+
+		var mediaFile *types.MediaFile
+		sourceID := mediaSourceID(item, labels)
+		if cached := storage.FindSource(sourceID); cached != nil {
+			// 同一文件此前已处理并上传，直接复用，无需重新下载
+			mediaFile = cached
+			mediaFile.Duration = item.Duration
+			mediaFile.Performer = item.Performer
+			mediaFile.Title = item.Title
+			mediaFile.Loop = item.Type == "animation"
+		} else {
+			// 下载到暂存文件，不在内存中保存完整内容
+			spooled, err := telegram.SpoolFile(bot, item.FileID, func(written, total int64) {
+				if total <= 0 {
+					total = item.FileSize
+				}
+				progress.Update(prefix + downloadProgress(written, total))
+			})
+			if err != nil {
+				restorePending(chatID, pending, content)
+				progress.Set("❌ 下载媒体文件失败\n\n💡 发送文字即可重新发布")
+				return err
+			}
+			defer spooled.Remove()
+
+			progress.Set(prefix + "⏳ 正在处理媒体文件...")
+			if mediaFile, err = buildMediaFile(item, spooled, timestamp); err != nil {
+				progress.Set("❌ 读取媒体文件失败")
+				return err
+			}
+			if item.FileName == "" {
+				mediaFile.Name = albumFileName(mediaFile.Name, i)
+			}
+			attachThumbnail(bot, item, mediaFile)
+			if item.Type == "sticker" {
+				convertSticker(mediaFile)
+			} else {
+				if watermarkEnabled(item, labels) {
+					applyWatermark(mediaFile)
+				}
+				if config.Cfg.ImageProcessing {
+					processImage(mediaFile)
+				}
+			}
+			analyzeImage(mediaFile)
+			mediaFile.SourceID = sourceID
+		}
+		mediaFile.Alt = altText(finalContent)
+		mediaFiles = append(mediaFiles, mediaFile)
 	}
 	
-	_, err = github.UploadToGitHubWithLabels(bot, finalContent, mediaFiles, labels)
+	status := &uploadStatus{progress: progress}
+	issue, uploaded, err := publishMoment(pending, finalContent, labels, mediaFiles, status.report)
 	if err != nil {
+		restorePending(chatID, pending, content)
+		status.finish("❌ 上传媒体文件或发布动态失败\n\n💡 发送文字即可重新发布，已上传的文件会直接复用")
 		return err
 	}
+	status.finish("✅ 媒体文件已上传")
+	
+	// 写入本地索引
+	store.PutIssue(issue)
 	
-	// 缓存已发布的动态信息（这里需要从响应中获取，暂时跳过）
-	// TODO: 修改 UploadToGitHubWithLabels 返回 Issue 信息
+	// 撤回时恢复的草稿，保留原始媒体文件
+	draft := *pending
+	if content != "" {
+		draft.Caption = content
+	}
 	
-	return safeSendMessage(bot, chatID, "✅ 动态发布成功！")
-} 
+	successMessage := fmt.Sprintf("✅ 动态发布成功！\n\n🔗 查看链接：%s", issue.HTMLURL)
+	return sendPublishSuccess(bot, chatID, successMessage, issue, draft, uploaded)
+}
+
+// restorePending 发布失败后放回待发布的媒体，用户发送文字即可重新发布，content 为空时沿用原来的文字。
+// 期间收到了新的待发布内容时不覆盖
+func restorePending(chatID int64, pending *types.PendingMedia, content string) {
+	if content != "" {
+		pending.Caption = content
+	}
+
+	config.MediaMutex.Lock()
+	defer config.MediaMutex.Unlock()
+	if _, exists := config.PendingMedia[chatID]; !exists {
+		config.PendingMedia[chatID] = pending
+	}
+}
+
+// publishMoment 上传媒体文件，使用模板生成标题和正文后创建 Issue，report 不为 nil 时报告每个文件的上传进度
+func publishMoment(pending *types.PendingMedia, content string, labels []string, mediaFiles []*types.MediaFile, report func(storage.UploadProgress)) (*types.GitHubIssueResponse, []types.UploadedFile, error) {
+	// 动态创建前上传的文件未被引用，期间暂停清理未引用文件
+	defer storage.BeginPublish()()
+
+	media, uploaded, err := storage.UploadMediaFiles(mediaFiles, report)
+	if err != nil {
+		return nil, uploaded, err
+	}
+
+	title, body, err := render.Render(render.NewPost(pending, content, labels, media))
+	if err != nil {
+		return nil, uploaded, err
+	}
+
+	issue, err := github.CreateGitHubIssueWithTitle(title, body, labels)
+	if err != nil {
+		return nil, uploaded, err
+	}
+	return issue, uploaded, nil
+}
+
+// mediaSourceID 来源文件的去重标识：Telegram FileUniqueID 加上处理方式，同一文件处理方式不同时结果不同
+func mediaSourceID(pending *types.PendingMedia, labels []string) string {
+	if pending.FileUniqueID == "" {
+		return ""
+	}
+	sourceID := pending.FileUniqueID
+	if pending.Type != "sticker" {
+		if watermarkEnabled(pending, labels) {
+			sourceID += "+watermark"
+		}
+		if config.Cfg.ImageProcessing {
+			sourceID += "+processed"
+		}
+	}
+	return sourceID
+}
+
+// messageSource 记录待发布内容对应的 Telegram 消息
+func messageSource(message *tgbotapi.Message) *types.MessageSource {
+	source := &types.MessageSource{
+		ChatID:    message.Chat.ID,
+		MessageID: message.MessageID,
+		Date:      int64(message.Date),
+	}
+	if message.From != nil {
+		source.AuthorID = message.From.ID
+		source.Username = message.From.UserName
+		source.AuthorName = strings.TrimSpace(message.From.FirstName + " " + message.From.LastName)
+	}
+	return source
+}
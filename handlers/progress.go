@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"moments-go/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// progressInterval 两次编辑进度消息的最小间隔，避免触发 Telegram 的频率限制
+const progressInterval = 2 * time.Second
+
+// progressMessage 通过编辑同一条消息报告发布进度，为 nil 时忽略所有更新
+type progressMessage struct {
+	bot       *tgbotapi.BotAPI
+	chatID    int64
+	messageID int
+
+	mutex     sync.Mutex
+	text      string    // 最新的进度
+	shown     string    // 消息当前显示的内容
+	scheduled bool      // 是否已安排延后编辑
+	edited    time.Time // 上次编辑的时间
+
+	sendMutex sync.Mutex // 保证编辑按顺序进行，消息最终显示最新的进度
+}
+
+// newProgressMessage 发送进度消息
+func newProgressMessage(bot *tgbotapi.BotAPI, chatID int64, text string) (*progressMessage, error) {
+	sent, err := bot.Send(tgbotapi.NewMessage(chatID, cleanUTF8String(text)))
+	if err != nil {
+		return nil, err
+	}
+	return &progressMessage{bot: bot, chatID: chatID, messageID: sent.MessageID, text: text, shown: text, edited: time.Now()}, nil
+}
+
+// Update 更新进度，用于频繁变化的下载和上传进度：距上次编辑不足 progressInterval 时延后编辑，
+// 期间的更新只显示最新的一次。不等待编辑完成，可以并发调用
+func (p *progressMessage) Update(text string) {
+	if p == nil {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.text = text
+	if p.scheduled {
+		return
+	}
+	p.scheduled = true
+	time.AfterFunc(max(progressInterval-time.Since(p.edited), 0), p.flush)
+}
+
+// Set 立即更新进度，用于阶段切换和最终结果
+func (p *progressMessage) Set(text string) {
+	if p == nil {
+		return
+	}
+
+	p.mutex.Lock()
+	p.text = text
+	p.mutex.Unlock()
+	p.flush()
+}
+
+// flush 将消息编辑为最新的进度
+func (p *progressMessage) flush() {
+	p.sendMutex.Lock()
+	defer p.sendMutex.Unlock()
+
+	p.mutex.Lock()
+	p.scheduled = false
+	text := p.text
+	if text == p.shown {
+		p.mutex.Unlock()
+		return
+	}
+	p.shown = text
+	p.edited = time.Now()
+	p.mutex.Unlock()
+
+	if err := sendOrEditMessage(p.bot, p.chatID, p.messageID, text, nil); err != nil {
+		log.Printf("更新进度消息失败: %v", err)
+	}
+}
+
+// downloadProgress 下载进度的说明，总大小未知时只显示已下载的大小
+func downloadProgress(written, total int64) string {
+	if total <= 0 {
+		return fmt.Sprintf("⬇️ 正在下载媒体文件：%s", storage.FormatBytes(written))
+	}
+	return fmt.Sprintf("⬇️ 正在下载媒体文件：%s / %s（%d%%）", storage.FormatBytes(written), storage.FormatBytes(total), written*100/total)
+}
+
+// uploadStatus 在进度消息中逐个显示文件的上传状态
+type uploadStatus struct {
+	progress *progressMessage
+	files    []storage.UploadProgress
+	mutex    sync.Mutex
+}
+
+// report 接收 storage.UploadMediaFiles 报告的进度并更新消息，上传字节数的变化延后显示，状态变化立即显示
+func (s *uploadStatus) report(update storage.UploadProgress) {
+	s.mutex.Lock()
+	for len(s.files) <= update.Index {
+		s.files = append(s.files, storage.UploadProgress{})
+	}
+	s.files[update.Index] = update
+	text := s.render()
+	s.mutex.Unlock()
+
+	if update.State == storage.UploadWaiting || update.State == storage.UploadRunning {
+		s.progress.Update(text)
+	} else {
+		s.progress.Set(text)
+	}
+}
+
+// finish 上传和发布结束后显示最终状态
+func (s *uploadStatus) finish(result string) {
+	s.mutex.Lock()
+	text := s.render() + "\n\n" + result
+	s.mutex.Unlock()
+	s.progress.Set(text)
+}
+
+// render 生成上传状态的说明，调用方需持有锁
+func (s *uploadStatus) render() string {
+	finished := 0
+	var lines []string
+	for _, file := range s.files {
+		if file.File == nil {
+			continue
+		}
+		if file.State == storage.UploadDone || file.State == storage.UploadReused {
+			finished++
+		}
+		lines = append(lines, describeUpload(file))
+	}
+	return fmt.Sprintf("📤 上传媒体文件（%d/%d）\n\n%s", finished, len(lines), strings.Join(lines, "\n"))
+}
+
+// describeUpload 单个文件的上传状态
+func describeUpload(file storage.UploadProgress) string {
+	name := file.File.Name
+	switch file.State {
+	case storage.UploadRunning:
+		if file.Size <= 0 {
+			return fmt.Sprintf("📤 %s：%s", name, storage.FormatBytes(file.Written))
+		}
+		return fmt.Sprintf("📤 %s：%d%%（%s / %s）", name, file.Written*100/file.Size, storage.FormatBytes(file.Written), storage.FormatBytes(file.Size))
+	case storage.UploadRetrying:
+		return fmt.Sprintf("🔁 %s：上传失败，准备第 %d 次尝试", name, file.Attempt+1)
+	case storage.UploadCommitting:
+		return fmt.Sprintf("📝 %s：等待提交", name)
+	case storage.UploadDone:
+		return fmt.Sprintf("✅ %s：已上传", name)
+	case storage.UploadReused:
+		return fmt.Sprintf("♻️ %s：已存在，直接复用", name)
+	case storage.UploadFailed:
+		return fmt.Sprintf("❌ %s：%s", name, truncateText(file.Err.Error(), 100))
+	default:
+		return fmt.Sprintf("⏳ %s：等待上传", name)
+	}
+}
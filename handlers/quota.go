@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"moments-go/config"
+	"moments-go/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// StartQuotaMonitor 启动文件仓库大小监控，超过提醒阈值时通知用户，设置了切换阈值时自动切换到新仓库
+func StartQuotaMonitor(bot *tgbotapi.BotAPI) {
+	if !storage.UsesFileRepo() || (len(config.Cfg.FileRepoWarnMB) == 0 && config.Cfg.FileRepoRolloverMB <= 0) {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(config.QuotaCheckInterval) * time.Second)
+		defer ticker.Stop()
+
+		for {
+			checkFileRepo(bot)
+			<-ticker.C
+		}
+	}()
+}
+
+// checkFileRepo 执行一次文件仓库大小检查，并通知用户检查结果
+func checkFileRepo(bot *tgbotapi.BotAPI) {
+	status, err := storage.CheckFileRepo()
+	if err != nil {
+		log.Printf("检查文件仓库失败: %v", err)
+		if status == nil {
+			return
+		}
+	}
+	if status.RolloverFailed {
+		// 之后每次检查都会重试切换，失败只通知一次
+		notifyQuota(bot, fmt.Sprintf("❌ 文件仓库 %s 已超过 %dMB，自动切换失败：%v", status.Repo, config.Cfg.FileRepoRolloverMB, err))
+	}
+
+	for _, threshold := range status.Warnings {
+		log.Printf("文件仓库 %s 已使用 %s，超过 %dMB", status.Repo, storage.FormatBytes(status.Size), threshold)
+		notifyQuota(bot, fmt.Sprintf("⚠️ 文件仓库 %s 已使用 %s，超过 %dMB 提醒阈值\n\nGitHub 建议仓库不超过 1GB，可以发送 /gc 清理未引用的文件，或设置 FILE_REPO_ROLLOVER_MB 自动切换到新仓库",
+			status.Repo, storage.FormatBytes(status.Size), threshold))
+	}
+
+	if status.RolledOver != "" {
+		log.Printf("文件仓库已从 %s 切换到 %s", status.Repo, status.RolledOver)
+		notifyQuota(bot, fmt.Sprintf("📦 文件仓库 %s 已使用 %s，新文件将上传到新仓库 %s\n\n旧仓库中的文件地址保持不变", status.Repo, storage.FormatBytes(status.Size), status.RolledOver))
+	}
+}
+
+func notifyQuota(bot *tgbotapi.BotAPI, message string) {
+	if err := safeSendMessage(bot, config.Cfg.TelegramUserID, message); err != nil {
+		log.Printf("发送文件仓库通知失败: %v", err)
+	}
+}
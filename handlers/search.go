@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"moments-go/config"
+	"moments-go/github"
+	"moments-go/store"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const searchUsage = `🔍 搜索动态
+
+用法：/search <关键词> [label:标签] [after:日期] [before:日期]
+
+例如：
+/search 咖啡
+/search 旅行 label:日常 after:2024-01-01 before:2024-07-01
+
+💡 日期格式为 2024-01-01 或 2024-01，after 包含当天，before 不包含当天`
+
+// HandleSearchCommand 处理 /search 命令
+func HandleSearchCommand(bot *tgbotapi.BotAPI, update tgbotapi.Update) error {
+	if !config.IsAuthorizedUser(update.Message.Chat.ID) {
+		return nil
+	}
+
+	args := strings.TrimSpace(strings.TrimPrefix(update.Message.Text, "/search"))
+	if args == "" {
+		return safeSendMessage(bot, update.Message.Chat.ID, searchUsage)
+	}
+
+	state, err := parseSearchQuery(args)
+	if err != nil {
+		return safeSendMessage(bot, update.Message.Chat.ID, fmt.Sprintf("❌ %v\n\n%s", err, searchUsage))
+	}
+
+	config.SetBrowseState(update.Message.Chat.ID, state)
+	return showMomentBrowser(bot, update.Message.Chat.ID, 0)
+}
+
+// parseSearchQuery 解析搜索参数，支持 label:、before:、after: 限定条件
+func parseSearchQuery(args string) (config.BrowseState, error) {
+	state := config.BrowseState{Page: 1, Search: true}
+	var keywords []string
+
+	for _, field := range strings.Fields(args) {
+		switch {
+		case strings.HasPrefix(field, "label:"):
+			state.Label = strings.TrimPrefix(field, "label:")
+		case strings.HasPrefix(field, "after:"):
+			date, err := parseSearchDate(strings.TrimPrefix(field, "after:"))
+			if err != nil {
+				return state, err
+			}
+			state.After = date
+		case strings.HasPrefix(field, "before:"):
+			date, err := parseSearchDate(strings.TrimPrefix(field, "before:"))
+			if err != nil {
+				return state, err
+			}
+			state.Before = date
+		default:
+			keywords = append(keywords, field)
+		}
+	}
+
+	state.Keywords = strings.Join(keywords, " ")
+	if state.Keywords == "" && state.Label == "" && state.After == "" && state.Before == "" {
+		return state, fmt.Errorf("请输入搜索条件")
+	}
+
+	return state, nil
+}
+
+// parseSearchDate 解析日期，支持 2006-01-02 和 2006-01（按当月第一天处理）
+func parseSearchDate(value string) (string, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t.Format("2006-01-02"), nil
+	}
+	if t, err := time.Parse("2006-01", value); err == nil {
+		return t.Format("2006-01-02"), nil
+	}
+	return "", fmt.Errorf("无效的日期：%s", value)
+}
+
+// searchMoments 搜索动态，GitHub 搜索接口不可用时回退到本地索引
+func searchMoments(state config.BrowseState) (*store.Page, error) {
+	opts := github.SearchOptions{
+		Keywords: state.Keywords,
+		Label:    state.Label,
+		After:    state.After,
+		Before:   state.Before,
+		Page:     state.Page,
+		PerPage:  browsePageSize,
+	}
+
+	page, err := github.SearchMoments(opts)
+	if err == nil {
+		return store.PageFromIssues(page), nil
+	}
+	log.Printf("GitHub 搜索失败: %v，使用本地索引搜索", err)
+
+	if err := store.SyncIfStale(browseSyncAge); err != nil {
+		log.Printf("同步动态索引失败: %v，使用现有索引", err)
+	}
+	return store.Search(opts), nil
+}
+
+// describeSearch 描述当前搜索条件
+func describeSearch(state config.BrowseState) string {
+	var parts []string
+	if state.Keywords != "" {
+		parts = append(parts, "关键词："+state.Keywords)
+	}
+	if state.Label != "" {
+		parts = append(parts, "🏷️ 标签："+state.Label)
+	}
+	if state.After != "" || state.Before != "" {
+		parts = append(parts, fmt.Sprintf("📅 时间：%s ~ %s", state.After, state.Before))
+	}
+	return strings.Join(parts, "　") + "\n"
+}
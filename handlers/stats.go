@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"moments-go/config"
+	"moments-go/store"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const statsMonthSpan = 6 // 统计最近几个月的发布数量
+
+// HandleStatsCommand 处理 /stats 命令
+func HandleStatsCommand(bot *tgbotapi.BotAPI, update tgbotapi.Update) error {
+	if !config.IsAuthorizedUser(update.Message.Chat.ID) {
+		return nil
+	}
+
+	if err := store.SyncIfStale(browseSyncAge); err != nil {
+		return safeSendMessage(bot, update.Message.Chat.ID, fmt.Sprintf("❌ 同步动态索引失败：%v", err))
+	}
+
+	all := store.Query(nil)
+	var openCount, closedCount, mediaCount int
+	labelCounts := make(map[string]int)
+	monthCounts := make(map[string]int)
+	for _, moment := range all {
+		if moment.State != "open" {
+			closedCount++
+			continue
+		}
+		openCount++
+		mediaCount += len(moment.MediaURLs)
+		for _, label := range moment.Labels {
+			labelCounts[label]++
+		}
+		monthCounts[time.Unix(moment.CreatedAt, 0).Format("2006-01")]++
+	}
+
+	message := "📊 动态统计\n\n"
+	message += fmt.Sprintf("📝 动态：%d 条\n", openCount)
+	message += fmt.Sprintf("🖼️ 媒体：%d 个\n", mediaCount)
+	message += fmt.Sprintf("🗑️ 回收站：%d 条\n", closedCount)
+
+	if len(labelCounts) > 0 {
+		labels := make([]string, 0, len(labelCounts))
+		for label := range labelCounts {
+			labels = append(labels, label)
+		}
+		sort.Slice(labels, func(i, j int) bool {
+			return labelCounts[labels[i]] > labelCounts[labels[j]]
+		})
+
+		message += "\n🏷️ 按标签：\n"
+		for _, label := range labels {
+			message += fmt.Sprintf("• %s：%d\n", label, labelCounts[label])
+		}
+	}
+
+	message += "\n📅 最近几个月：\n"
+	now := time.Now()
+	current := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	for i := 0; i < statsMonthSpan; i++ {
+		month := current.AddDate(0, -i, 0).Format("2006-01")
+		message += fmt.Sprintf("• %s：%d\n", month, monthCounts[month])
+	}
+
+	return safeSendMessage(bot, update.Message.Chat.ID, message)
+}
+
+// HandleSyncCommand 处理 /sync 命令，/sync full 会重建整个索引
+func HandleSyncCommand(bot *tgbotapi.BotAPI, update tgbotapi.Update) error {
+	if !config.IsAuthorizedUser(update.Message.Chat.ID) {
+		return nil
+	}
+
+	full := update.Message.CommandArguments() == "full"
+	if err := safeSendMessage(bot, update.Message.Chat.ID, "🔄 正在同步动态索引..."); err != nil {
+		return err
+	}
+
+	var count int
+	var err error
+	if full {
+		count, err = store.Resync()
+	} else {
+		count, err = store.Sync()
+	}
+	if err != nil {
+		return safeSendMessage(bot, update.Message.Chat.ID, fmt.Sprintf("❌ 同步失败：%v", err))
+	}
+
+	return safeSendMessage(bot, update.Message.Chat.ID, fmt.Sprintf("✅ 同步完成，更新了 %d 条动态", count))
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"moments-go/config"
+	"moments-go/github"
+	"moments-go/metadata"
+	"moments-go/storage"
+	"moments-go/store"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// HandleTrashCommand 处理 /trash 命令
+func HandleTrashCommand(bot *tgbotapi.BotAPI, update tgbotapi.Update) error {
+	if !config.IsAuthorizedUser(update.Message.Chat.ID) {
+		return nil
+	}
+	return showTrash(bot, update.Message.Chat.ID, 0, 1)
+}
+
+// showTrash 显示回收站中已删除的动态，messageID 不为 0 时编辑原消息
+func showTrash(bot *tgbotapi.BotAPI, chatID int64, messageID int, pageNumber int) error {
+	page, err := github.ListIssues(github.ListIssuesOptions{
+		State:   "closed",
+		Sort:    "updated",
+		Page:    pageNumber,
+		PerPage: browsePageSize,
+	})
+	if err != nil {
+		return sendOrEditMessage(bot, chatID, messageID, fmt.Sprintf("❌ 获取回收站失败：%v", err), nil)
+	}
+
+	message := fmt.Sprintf("🗑️ 回收站（第 %d/%d 页）\n", page.Page, page.LastPage())
+	if config.Cfg.TrashRetentionDays > 0 {
+		message += fmt.Sprintf("⏳ 删除超过 %d 天的动态会被永久清理\n", config.Cfg.TrashRetentionDays)
+	}
+	message += "\n"
+
+	if len(page.Issues) == 0 {
+		message += "📝 回收站是空的"
+		return sendOrEditMessage(bot, chatID, messageID, message, nil)
+	}
+
+	var buttons [][]tgbotapi.InlineKeyboardButton
+	for _, issue := range page.Issues {
+		message += fmt.Sprintf("#%d · 删除于 %s\n%s\n\n", issue.Number, formatIssueDate(issue.ClosedAt), truncateText(metadata.Strip(issue.Body), 50))
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("♻️ 恢复 #%d", issue.Number), fmt.Sprintf("trash:restore:%d", issue.Number)),
+		))
+	}
+
+	var navRow []tgbotapi.InlineKeyboardButton
+	if page.HasPrev() {
+		navRow = append(navRow, tgbotapi.NewInlineKeyboardButtonData("⬅️ 上一页", fmt.Sprintf("trash:page:%d", page.Links.Prev)))
+	}
+	if page.HasNext() {
+		navRow = append(navRow, tgbotapi.NewInlineKeyboardButtonData("下一页 ➡️", fmt.Sprintf("trash:page:%d", page.Links.Next)))
+	}
+	if len(navRow) > 0 {
+		buttons = append(buttons, navRow)
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(buttons...)
+	return sendOrEditMessage(bot, chatID, messageID, message, &keyboard)
+}
+
+// handleTrashCallback 处理回收站的回调
+func handleTrashCallback(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery) error {
+	chatID := callback.From.ID
+	messageID := callback.Message.MessageID
+	action := strings.TrimPrefix(callback.Data, "trash:")
+
+	if strings.HasPrefix(action, "page:") {
+		answerCallback(bot, callback, "")
+		page, err := strconv.Atoi(strings.TrimPrefix(action, "page:"))
+		if err != nil {
+			return nil
+		}
+		return showTrash(bot, chatID, messageID, page)
+	}
+
+	if strings.HasPrefix(action, "restore:") {
+		issueNumber, err := strconv.Atoi(strings.TrimPrefix(action, "restore:"))
+		if err != nil {
+			answerCallback(bot, callback, "❌ 无效的动态编号")
+			return nil
+		}
+
+		issue, err := github.RestoreGitHubIssue(issueNumber)
+		if err != nil {
+			answerCallback(bot, callback, "❌ 恢复失败")
+			return safeSendMessage(bot, chatID, fmt.Sprintf("❌ 恢复动态 #%d 失败：%v", issueNumber, err))
+		}
+
+		store.PutIssue(issue)
+		answerCallback(bot, callback, fmt.Sprintf("✅ 动态 #%d 已恢复", issueNumber))
+		if err := showTrash(bot, chatID, messageID, 1); err != nil {
+			log.Printf("刷新回收站失败: %v", err)
+		}
+		return safeSendMessage(bot, chatID, fmt.Sprintf("♻️ 动态 #%d 已恢复！\n\n🔗 查看链接：%s", issueNumber, issue.HTMLURL))
+	}
+
+	answerCallback(bot, callback, "")
+	return nil
+}
+
+// StartTrashPurger 启动回收站定时清理，永久删除超过保留天数的动态
+func StartTrashPurger(bot *tgbotapi.BotAPI) {
+	if config.Cfg.TrashRetentionDays <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(config.TrashPurgeInterval) * time.Second)
+		defer ticker.Stop()
+
+		for {
+			purgeTrash(bot)
+			<-ticker.C
+		}
+	}()
+}
+
+// purgeTrash 执行一次回收站清理，并通知用户清理结果
+func purgeTrash(bot *tgbotapi.BotAPI) {
+	purged, err := storage.PurgeExpiredMoments(config.Cfg.TrashRetentionDays)
+	if err != nil {
+		log.Printf("清理回收站失败: %v", err)
+	}
+	if len(purged) == 0 {
+		return
+	}
+
+	var numbers []string
+	for _, number := range purged {
+		store.Remove(number)
+		numbers = append(numbers, fmt.Sprintf("#%d", number))
+	}
+	log.Printf("已永久删除回收站中的动态: %s", strings.Join(numbers, ", "))
+
+	message := fmt.Sprintf("🧹 已永久删除回收站中超过 %d 天的动态：%s", config.Cfg.TrashRetentionDays, strings.Join(numbers, ", "))
+	if err := safeSendMessage(bot, config.Cfg.TelegramUserID, message); err != nil {
+		log.Printf("发送清理通知失败: %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"moments-go/config"
+	"moments-go/github"
+	"moments-go/storage"
+	"moments-go/store"
+	"moments-go/types"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// sendPublishSuccess 发送发布成功消息，并附带在撤回时间窗口内有效的撤回按钮
+func sendPublishSuccess(bot *tgbotapi.BotAPI, chatID int64, message string, issue *types.GitHubIssueResponse, draft types.PendingMedia, uploaded []types.UploadedFile) error {
+	if config.Cfg.UndoWindow <= 0 {
+		return safeSendMessage(bot, chatID, message)
+	}
+
+	window := time.Duration(config.Cfg.UndoWindow) * time.Second
+	config.SetUndoRecord(&config.UndoRecord{
+		ChatID:      chatID,
+		IssueNumber: issue.Number,
+		NodeID:      issue.NodeID,
+		Draft:       draft,
+		Files:       uploaded,
+		ExpiresAt:   time.Now().Add(window).Unix(),
+	})
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("↩️ 撤回", fmt.Sprintf("undo:%d", issue.Number)),
+		),
+	)
+	text := message + fmt.Sprintf("\n\n↩️ %s内可以撤回", formatWindow(config.Cfg.UndoWindow))
+
+	msg := tgbotapi.NewMessage(chatID, cleanUTF8String(text))
+	msg.ReplyMarkup = keyboard
+	sent, err := bot.Send(msg)
+	if err != nil {
+		return err
+	}
+
+	// 时间窗口结束后移除撤回按钮
+	time.AfterFunc(window, func() {
+		if _, exists := config.TakeUndoRecord(issue.Number); !exists {
+			return
+		}
+		expired := tgbotapi.NewEditMessageText(chatID, sent.MessageID, cleanUTF8String(message))
+		if _, err := bot.Send(expired); err != nil {
+			log.Printf("移除撤回按钮失败: %v", err)
+		}
+	})
+
+	return nil
+}
+
+// handleUndoCallback 处理撤回按钮回调
+func handleUndoCallback(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery) error {
+	chatID := callback.From.ID
+	messageID := callback.Message.MessageID
+
+	issueNumber, err := strconv.Atoi(strings.TrimPrefix(callback.Data, "undo:"))
+	if err != nil {
+		answerCallback(bot, callback, "❌ 无效的动态编号")
+		return nil
+	}
+
+	record, exists := config.TakeUndoRecord(issueNumber)
+	if !exists || record.Expired() {
+		answerCallback(bot, callback, "⌛ 已超过撤回时间")
+		return sendOrEditMessage(bot, chatID, messageID, fmt.Sprintf("⌛ 动态 #%d 已超过撤回时间，可以使用 /delete %d 删除", issueNumber, issueNumber), nil)
+	}
+
+	// 已有新的待发布内容时不覆盖，保留撤回记录
+	config.MediaMutex.RLock()
+	_, hasPending := config.PendingMedia[chatID]
+	config.MediaMutex.RUnlock()
+	if hasPending {
+		config.SetUndoRecord(record)
+		answerCallback(bot, callback, "❌ 当前有待发布的内容，请先发布或取消")
+		return nil
+	}
+
+	answerCallback(bot, callback, "")
+	if err := sendOrEditMessage(bot, chatID, messageID, "⏳ 正在撤回动态...", nil); err != nil {
+		log.Printf("更新撤回进度失败: %v", err)
+	}
+
+	if err := undoPublish(record); err != nil {
+		return sendOrEditMessage(bot, chatID, messageID, fmt.Sprintf("❌ 撤回动态 #%d 失败：%v", issueNumber, err), nil)
+	}
+
+	// 恢复为草稿
+	draft := record.Draft
+	config.MediaMutex.Lock()
+	config.PendingMedia[chatID] = &draft
+	config.MediaMutex.Unlock()
+
+	if err := sendOrEditMessage(bot, chatID, messageID, fmt.Sprintf("↩️ 动态 #%d 已撤回", issueNumber), nil); err != nil {
+		log.Printf("更新撤回结果失败: %v", err)
+	}
+
+	message := "📝 内容已恢复为草稿！"
+	if draft.Caption != "" {
+		message += fmt.Sprintf("\n\n当前文字：%s", draft.Caption)
+	}
+	message += "\n\n💡 请选择标签重新发布，或发送文字修改内容后发布！"
+
+	msg := tgbotapi.NewMessage(chatID, cleanUTF8String(message))
+	msg.ReplyMarkup = createMediaLabelKeyboard(&draft)
+	_, err = bot.Send(msg)
+	return err
+}
+
+// undoPublish 删除刚发布的动态及其上传的文件
+func undoPublish(record *config.UndoRecord) error {
+	// 优先永久删除，失败时退回到关闭 Issue
+	if err := github.PurgeGitHubIssue(record.NodeID); err != nil {
+		log.Printf("永久删除动态 #%d 失败: %v，改为关闭", record.IssueNumber, err)
+		if err := github.DeleteGitHubIssue(record.IssueNumber); err != nil {
+			return err
+		}
+		store.SetState(record.IssueNumber, "closed")
+	} else {
+		store.Remove(record.IssueNumber)
+	}
+
+	storage.DeleteFiles(record.IssueNumber, record.Files)
+
+	return nil
+}
+
+// formatWindow 将秒数格式化为可读的时间长度
+func formatWindow(seconds int) string {
+	if seconds%60 == 0 {
+		return fmt.Sprintf("%d 分钟", seconds/60)
+	}
+	return fmt.Sprintf("%d 秒", seconds)
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"log"
 	"strings"
 	"unicode/utf8"
 	"moments-go/github"
@@ -34,4 +35,38 @@ func cleanUTF8String(s string) string {
 func safeSendMessage(bot *tgbotapi.BotAPI, chatID int64, message string) error {
 	cleanedMessage := cleanUTF8String(message)
 	return github.SendMessage(bot, chatID, cleanedMessage)
-} 
+}
+
+// truncateText 按字符截断文本，超出部分用省略号代替
+func truncateText(s string, maxRunes int) string {
+	runes := []rune(s)
+	if len(runes) <= maxRunes {
+		return s
+	}
+	return string(runes[:maxRunes]) + "..."
+}
+
+// sendOrEditMessage messageID 为 0 时发送新消息，否则编辑原消息
+func sendOrEditMessage(bot *tgbotapi.BotAPI, chatID int64, messageID int, text string, keyboard *tgbotapi.InlineKeyboardMarkup) error {
+	text = cleanUTF8String(text)
+	if messageID == 0 {
+		msg := tgbotapi.NewMessage(chatID, text)
+		if keyboard != nil {
+			msg.ReplyMarkup = *keyboard
+		}
+		_, err := bot.Send(msg)
+		return err
+	}
+
+	msg := tgbotapi.NewEditMessageText(chatID, messageID, text)
+	msg.ReplyMarkup = keyboard
+	_, err := bot.Send(msg)
+	return err
+}
+
+// answerCallback 应答回调查询，避免按钮一直处于加载状态
+func answerCallback(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery, text string) {
+	if _, err := bot.Request(tgbotapi.NewCallback(callback.ID, text)); err != nil {
+		log.Printf("应答回调查询失败: %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"log"
+	"path/filepath"
+	"strings"
+
+	"moments-go/config"
+	"moments-go/media"
+	"moments-go/types"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// watermark 配置的水印，未配置时为 nil
+var watermark *media.Watermark
+
+// LoadWatermark 根据配置加载水印文字或图标
+func LoadWatermark() error {
+	if config.Cfg.WatermarkText == "" && config.Cfg.WatermarkLogo == "" {
+		return nil
+	}
+
+	loaded, err := media.NewWatermark(
+		config.Cfg.WatermarkText,
+		config.Cfg.WatermarkLogo,
+		config.Cfg.WatermarkFont,
+		config.Cfg.WatermarkPosition,
+		config.Cfg.WatermarkOpacity,
+		config.Cfg.WatermarkScale,
+	)
+	if err != nil {
+		return err
+	}
+	watermark = loaded
+	return nil
+}
+
+// watermarkApplicable 待发布的内容是否可以添加水印（图片或以文件形式发送的图片）
+func watermarkApplicable(pending *types.PendingMedia) bool {
+	if watermark == nil {
+		return false
+	}
+	return pending.Type == "photo" || (pending.Type == "document" && strings.HasPrefix(pending.MimeType, "image/"))
+}
+
+// watermarkEnabled 是否为这条动态添加水印：优先使用手动切换的设置，否则按标签默认设置
+func watermarkEnabled(pending *types.PendingMedia, labels []string) bool {
+	if !watermarkApplicable(pending) {
+		return false
+	}
+	if pending.Watermark != nil {
+		return *pending.Watermark
+	}
+	if len(config.Cfg.WatermarkLabels) == 0 {
+		return true
+	}
+	for _, label := range labels {
+		for _, watermarkLabel := range config.Cfg.WatermarkLabels {
+			if label == watermarkLabel {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// applyWatermark 在图片上添加水印，失败时保留原图
+func applyWatermark(file *types.MediaFile) {
+	switch file.Type {
+	case "image/jpeg", "image/png", "image/webp":
+	default:
+		return
+	}
+
+	content, mimeType, err := watermark.Apply(file.Content, file.Type, config.Cfg.ImageQuality)
+	if err != nil {
+		log.Printf("添加水印到 %s 失败: %v", file.Name, err)
+		return
+	}
+	if mimeType != file.Type {
+		file.Name = strings.TrimSuffix(file.Name, filepath.Ext(file.Name)) + "." + extensionForMimeType(mimeType, "")
+	}
+	file.Content = content
+	file.Type = mimeType
+}
+
+// describeWatermark 水印设置的说明
+func describeWatermark(pending *types.PendingMedia) string {
+	switch {
+	case pending.Watermark == nil:
+		return "💧 水印：按标签默认"
+	case *pending.Watermark:
+		return "💧 水印：开"
+	default:
+		return "💧 水印：关"
+	}
+}
+
+// createMediaLabelKeyboard 创建标签选择键盘，可以添加水印的内容附带水印开关
+func createMediaLabelKeyboard(pending *types.PendingMedia) tgbotapi.InlineKeyboardMarkup {
+	keyboard := createLabelKeyboard()
+	if pending == nil || !watermarkApplicable(pending) {
+		return keyboard
+	}
+
+	row := tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(describeWatermark(pending), "label:watermark"))
+	// 放在刷新和取消按钮之前
+	last := len(keyboard.InlineKeyboard) - 1
+	keyboard.InlineKeyboard = append(keyboard.InlineKeyboard[:last], row, keyboard.InlineKeyboard[last])
+	return keyboard
+}
+
+// handleWatermarkToggle 切换待发布内容的水印设置：按标签默认 → 开 → 关 → 按标签默认
+func handleWatermarkToggle(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery) error {
+	chatID := callback.From.ID
+
+	config.MediaMutex.Lock()
+	pending, exists := config.PendingMedia[chatID]
+	if exists {
+		switch {
+		case pending.Watermark == nil:
+			enabled := true
+			pending.Watermark = &enabled
+		case *pending.Watermark:
+			enabled := false
+			pending.Watermark = &enabled
+		default:
+			pending.Watermark = nil
+		}
+	}
+	config.MediaMutex.Unlock()
+
+	if !exists {
+		answerCallback(bot, callback, "❌ 没有待处理的内容")
+		return nil
+	}
+
+	answerCallback(bot, callback, describeWatermark(pending))
+	markup := tgbotapi.NewEditMessageReplyMarkup(chatID, callback.Message.MessageID, createMediaLabelKeyboard(pending))
+	_, err := bot.Send(markup)
+	return err
+}
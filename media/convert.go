@@ -0,0 +1,24 @@
+package media
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+
+	"golang.org/x/image/webp"
+)
+
+// WebPToPNG 将 WebP 图片（如静态贴纸）转换为 PNG，保留透明通道
+func WebPToPNG(content []byte) ([]byte, error) {
+	img, err := webp.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("解码 WebP 失败: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("编码 PNG 失败: %v", err)
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,254 @@
+package media
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/webp"
+)
+
+// ImageOptions 图片处理参数
+type ImageOptions struct {
+	MaxEdge       int // 最长边上限（像素），0 表示不缩放
+	Quality       int // JPEG 编码质量（1-100）
+	ThumbnailEdge int // 缩略图最长边（像素），0 表示不生成缩略图
+}
+
+// ProcessedImage 处理后的图片
+type ProcessedImage struct {
+	Content   []byte
+	Type      string // image/jpeg 或 image/png
+	Width     int
+	Height    int
+	Thumbnail []byte // JPEG 缩略图，未生成时为 nil
+}
+
+// ProcessImage 重新编码图片：按 EXIF 方向摆正后丢弃全部元数据（包括 GPS），缩放到最长边上限，
+// 并生成缩略图。PNG 和带透明通道的 WebP 输出 PNG，其他输出 JPEG
+func ProcessImage(content []byte, mimeType string, opts ImageOptions) (*ProcessedImage, error) {
+	img, err := decodeImage(content, mimeType)
+	if err != nil {
+		return nil, err
+	}
+
+	img = scaleToFit(img, opts.MaxEdge)
+	if mimeType == "image/jpeg" {
+		img = applyOrientation(img, jpegOrientation(content))
+	}
+
+	result := &ProcessedImage{
+		Width:  img.Bounds().Dx(),
+		Height: img.Bounds().Dy(),
+	}
+
+	var buf bytes.Buffer
+	if mimeType == "image/png" || (mimeType == "image/webp" && !isOpaque(img)) {
+		err = png.Encode(&buf, img)
+		result.Type = "image/png"
+	} else {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality(opts.Quality)})
+		result.Type = "image/jpeg"
+	}
+	if err != nil {
+		return nil, fmt.Errorf("编码图片失败: %v", err)
+	}
+	result.Content = buf.Bytes()
+
+	if opts.ThumbnailEdge > 0 {
+		var thumb bytes.Buffer
+		if err := jpeg.Encode(&thumb, flatten(scaleToFit(img, opts.ThumbnailEdge)), &jpeg.Options{Quality: quality(opts.Quality)}); err != nil {
+			return nil, fmt.Errorf("生成缩略图失败: %v", err)
+		}
+		result.Thumbnail = thumb.Bytes()
+	}
+
+	return result, nil
+}
+
+// decodeImage 解码 JPEG、PNG、GIF（第一帧）和 WebP 图片
+func decodeImage(content []byte, mimeType string) (image.Image, error) {
+	var img image.Image
+	var err error
+	switch mimeType {
+	case "image/jpeg":
+		img, err = jpeg.Decode(bytes.NewReader(content))
+	case "image/png":
+		img, err = png.Decode(bytes.NewReader(content))
+	case "image/gif":
+		img, err = gif.Decode(bytes.NewReader(content))
+	case "image/webp":
+		img, err = webp.Decode(bytes.NewReader(content))
+	default:
+		return nil, fmt.Errorf("不支持处理 %s 图片", mimeType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("解码图片失败: %v", err)
+	}
+	return img, nil
+}
+
+// quality 规范 JPEG 编码质量
+func quality(q int) int {
+	if q <= 0 || q > 100 {
+		return jpeg.DefaultQuality
+	}
+	return q
+}
+
+// scaleToFit 等比缩小图片使最长边不超过 maxEdge，图片已经足够小时原样返回
+func scaleToFit(img image.Image, maxEdge int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if maxEdge <= 0 || (width <= maxEdge && height <= maxEdge) {
+		return img
+	}
+
+	if width >= height {
+		height = max(1, height*maxEdge/width)
+		width = maxEdge
+	} else {
+		width = max(1, width*maxEdge/height)
+		height = maxEdge
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst
+}
+
+// flatten 将透明区域铺上白色背景，用于编码 JPEG 缩略图
+func flatten(img image.Image) image.Image {
+	if isOpaque(img) {
+		return img
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, img.Bounds().Min, draw.Over)
+	return dst
+}
+
+// isOpaque 判断图片是否完全不透明
+func isOpaque(img image.Image) bool {
+	if opaque, ok := img.(interface{ Opaque() bool }); ok {
+		return opaque.Opaque()
+	}
+	return false
+}
+
+// toRGBA 将图片转换为 RGBA，方便逐像素处理
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Bounds().Min == (image.Point{}) {
+		return rgba
+	}
+	bounds := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Src)
+	return dst
+}
+
+// applyOrientation 按 EXIF 方向（1-8）旋转或翻转图片，重新编码后 EXIF 会丢失，需要先摆正
+func applyOrientation(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	src := toRGBA(img)
+	width, height := src.Bounds().Dx(), src.Bounds().Dy()
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 {
+		dstWidth, dstHeight = height, width
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // 水平翻转
+				dx, dy = width-1-x, y
+			case 3: // 旋转 180°
+				dx, dy = width-1-x, height-1-y
+			case 4: // 垂直翻转
+				dx, dy = x, height-1-y
+			case 5: // 沿主对角线翻转
+				dx, dy = y, x
+			case 6: // 顺时针旋转 90°
+				dx, dy = height-1-y, x
+			case 7: // 沿副对角线翻转
+				dx, dy = height-1-y, width-1-x
+			case 8: // 逆时针旋转 90°
+				dx, dy = y, width-1-x
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):dst.PixOffset(dx, dy)+4], src.Pix[src.PixOffset(x, y):src.PixOffset(x, y)+4])
+		}
+	}
+
+	return dst
+}
+
+// jpegOrientation 读取 JPEG 中 EXIF 的方向标记，没有时返回 1
+func jpegOrientation(content []byte) int {
+	if len(content) < 4 || content[0] != 0xFF || content[1] != 0xD8 {
+		return 1
+	}
+
+	offset := 2
+	for offset+4 <= len(content) {
+		if content[offset] != 0xFF {
+			return 1
+		}
+		marker := content[offset+1]
+		length := int(content[offset+2])<<8 | int(content[offset+3])
+		if marker == 0xDA || length < 2 || offset+2+length > len(content) {
+			// 图像数据开始或数据损坏
+			return 1
+		}
+		segment := content[offset+4 : offset+2+length]
+		if marker == 0xE1 && len(segment) > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return exifOrientation(segment[6:])
+		}
+		offset += 2 + length
+	}
+	return 1
+}
+
+// exifOrientation 从 TIFF 结构的 IFD0 中读取方向标记（0x0112）
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var read16 func([]byte) int
+	var read32 func([]byte) int
+	switch string(tiff[:2]) {
+	case "II":
+		read16 = func(b []byte) int { return int(b[0]) | int(b[1])<<8 }
+		read32 = func(b []byte) int { return int(b[0]) | int(b[1])<<8 | int(b[2])<<16 | int(b[3])<<24 }
+	case "MM":
+		read16 = func(b []byte) int { return int(b[0])<<8 | int(b[1]) }
+		read32 = func(b []byte) int { return int(b[0])<<24 | int(b[1])<<16 | int(b[2])<<8 | int(b[3]) }
+	default:
+		return 1
+	}
+
+	ifd := read32(tiff[4:8])
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	count := read16(tiff[ifd:])
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if read16(tiff[entry:]) == 0x0112 {
+			return read16(tiff[entry+8:])
+		}
+	}
+	return 1
+}
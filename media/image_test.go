@@ -0,0 +1,125 @@
+package media
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// gridImage 按字母网格生成图片，每个字母对应一种颜色，便于比较像素位置
+func gridImage(rows ...string) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, len(rows[0]), len(rows)))
+	for y, row := range rows {
+		for x, cell := range row {
+			img.Set(x, y, color.RGBA{R: uint8(cell), G: 255 - uint8(cell), B: 0, A: 255})
+		}
+	}
+	return img
+}
+
+// gridRows 将图片还原为字母网格
+func gridRows(img image.Image) []string {
+	bounds := img.Bounds()
+	rows := make([]string, 0, bounds.Dy())
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		row := make([]byte, 0, bounds.Dx())
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, _, _, _ := img.At(x, y).RGBA()
+			row = append(row, byte(r>>8))
+		}
+		rows = append(rows, string(row))
+	}
+	return rows
+}
+
+func TestApplyOrientation(t *testing.T) {
+	// 3x2 的非对称图片：
+	//   ABC
+	//   DEF
+	source := []string{"ABC", "DEF"}
+
+	tests := []struct {
+		orientation int
+		want        []string
+	}{
+		{0, []string{"ABC", "DEF"}},
+		{1, []string{"ABC", "DEF"}},
+		{2, []string{"CBA", "FED"}},
+		{3, []string{"FED", "CBA"}},
+		{4, []string{"DEF", "ABC"}},
+		{5, []string{"AD", "BE", "CF"}},
+		{6, []string{"DA", "EB", "FC"}},
+		{7, []string{"FC", "EB", "DA"}},
+		{8, []string{"CF", "BE", "AD"}},
+		{9, []string{"ABC", "DEF"}},
+	}
+
+	for _, tt := range tests {
+		got := gridRows(applyOrientation(gridImage(source...), tt.orientation))
+		if len(got) != len(tt.want) {
+			t.Errorf("orientation %d: got %q, want %q", tt.orientation, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("orientation %d: got %q, want %q", tt.orientation, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+// exifJPEG 生成只包含 EXIF 段的 JPEG 头部，方向标记为 orientation
+func exifJPEG(byteOrder string, orientation int) []byte {
+	put16 := func(b []byte, v int) { b[0], b[1] = byte(v>>8), byte(v) }
+	if byteOrder == "II" {
+		put16 = func(b []byte, v int) { b[0], b[1] = byte(v), byte(v>>8) }
+	}
+
+	tiff := make([]byte, 8+2+12)
+	copy(tiff, byteOrder)
+	put16(tiff[2:], 42)
+	if byteOrder == "II" {
+		tiff[4] = 8
+	} else {
+		tiff[7] = 8
+	}
+	put16(tiff[8:], 1)
+	put16(tiff[10:], 0x0112) // 方向标记
+	put16(tiff[12:], 3)      // SHORT
+	// 数量为 1（LONG）
+	if byteOrder == "II" {
+		tiff[14] = 1
+	} else {
+		tiff[17] = 1
+	}
+	put16(tiff[18:], orientation)
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	length := len(segment) + 2
+	content := []byte{0xFF, 0xD8, 0xFF, 0xE1, byte(length >> 8), byte(length)}
+	content = append(content, segment...)
+	return append(content, 0xFF, 0xDA, 0x00, 0x02)
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		want    int
+	}{
+		{"little endian", exifJPEG("II", 6), 6},
+		{"big endian", exifJPEG("MM", 8), 8},
+		{"no exif", []byte{0xFF, 0xD8, 0xFF, 0xDA, 0x00, 0x02}, 1},
+		{"not jpeg", []byte("\x89PNG\r\n\x1a\n"), 1},
+		{"truncated", exifJPEG("II", 3)[:12], 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jpegOrientation(tt.content); got != tt.want {
+				t.Errorf("jpegOrientation() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package media
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"strings"
+)
+
+// Placeholder 前端在图片加载前显示占位所需的信息
+type Placeholder struct {
+	Width         int    // 按 EXIF 方向摆正后的宽度
+	Height        int    // 按 EXIF 方向摆正后的高度
+	DominantColor string // 主色，格式 #rrggbb
+	BlurHash      string
+}
+
+// placeholderEdge 计算主色和 BlurHash 前先缩小到的最长边，足够精确且速度快
+const placeholderEdge = 64
+
+// AnalyzeImage 计算图片的尺寸、主色和 BlurHash
+func AnalyzeImage(content []byte, mimeType string) (*Placeholder, error) {
+	img, err := decodeImage(content, mimeType)
+	if err != nil {
+		return nil, err
+	}
+
+	orientation := 1
+	if mimeType == "image/jpeg" {
+		orientation = jpegOrientation(content)
+	}
+	small := applyOrientation(scaleToFit(img, placeholderEdge), orientation)
+
+	placeholder := &Placeholder{
+		Width:  img.Bounds().Dx(),
+		Height: img.Bounds().Dy(),
+	}
+	if orientation >= 5 {
+		// 按 EXIF 方向旋转了 90°
+		placeholder.Width, placeholder.Height = placeholder.Height, placeholder.Width
+	}
+
+	rgba := toRGBA(flatten(small))
+	placeholder.DominantColor = dominantColor(rgba)
+	placeholder.BlurHash = blurHash(rgba)
+	return placeholder, nil
+}
+
+// dominantColor 将颜色按每通道 4 位量化后统计出现最多的颜色区间，返回该区间的平均色
+func dominantColor(img *image.RGBA) string {
+	type bucket struct {
+		count   int
+		r, g, b int
+	}
+	buckets := make(map[int]*bucket)
+	var best *bucket
+
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			offset := img.PixOffset(x, y)
+			r, g, b := int(img.Pix[offset]), int(img.Pix[offset+1]), int(img.Pix[offset+2])
+			key := (r>>4)<<8 | (g>>4)<<4 | b>>4
+			item, exists := buckets[key]
+			if !exists {
+				item = &bucket{}
+				buckets[key] = item
+			}
+			item.count++
+			item.r += r
+			item.g += g
+			item.b += b
+			if best == nil || item.count > best.count {
+				best = item
+			}
+		}
+	}
+
+	if best == nil {
+		return "#000000"
+	}
+	return fmt.Sprintf("#%02x%02x%02x", best.r/best.count, best.g/best.count, best.b/best.count)
+}
+
+// blurHash 按 https://blurha.sh 的算法编码图片，横向图片使用 4x3 分量，纵向图片使用 3x4
+func blurHash(img *image.RGBA) string {
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+	xComponents, yComponents := 4, 3
+	if height > width {
+		xComponents, yComponents = 3, 4
+	}
+
+	factors := make([][3]float64, 0, xComponents*yComponents)
+	for j := 0; j < yComponents; j++ {
+		for i := 0; i < xComponents; i++ {
+			normalisation := 2.0
+			if i == 0 && j == 0 {
+				normalisation = 1
+			}
+			var r, g, b float64
+			for y := 0; y < height; y++ {
+				for x := 0; x < width; x++ {
+					basis := math.Cos(math.Pi*float64(i)*float64(x)/float64(width)) *
+						math.Cos(math.Pi*float64(j)*float64(y)/float64(height))
+					offset := img.PixOffset(x, y)
+					r += basis * srgbToLinear(img.Pix[offset])
+					g += basis * srgbToLinear(img.Pix[offset+1])
+					b += basis * srgbToLinear(img.Pix[offset+2])
+				}
+			}
+			scale := normalisation / float64(width*height)
+			factors = append(factors, [3]float64{r * scale, g * scale, b * scale})
+		}
+	}
+
+	var hash strings.Builder
+	hash.WriteString(encode83((xComponents-1)+(yComponents-1)*9, 1))
+
+	maximumValue := 1.0
+	ac := factors[1:]
+	if len(ac) > 0 {
+		actualMaximum := 0.0
+		for _, factor := range ac {
+			for _, value := range factor {
+				actualMaximum = math.Max(actualMaximum, math.Abs(value))
+			}
+		}
+		quantisedMaximum := int(math.Max(0, math.Min(82, math.Floor(actualMaximum*166-0.5))))
+		maximumValue = float64(quantisedMaximum+1) / 166
+		hash.WriteString(encode83(quantisedMaximum, 1))
+	} else {
+		hash.WriteString(encode83(0, 1))
+	}
+
+	dc := factors[0]
+	hash.WriteString(encode83(linearToSRGB(dc[0])<<16|linearToSRGB(dc[1])<<8|linearToSRGB(dc[2]), 4))
+
+	for _, factor := range ac {
+		quantise := func(value float64) int {
+			return int(math.Max(0, math.Min(18, math.Floor(signPow(value/maximumValue, 0.5)*9+9.5))))
+		}
+		hash.WriteString(encode83(quantise(factor[0])*19*19+quantise(factor[1])*19+quantise(factor[2]), 2))
+	}
+
+	return hash.String()
+}
+
+const base83Characters = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+// encode83 BlurHash 使用的 base83 编码
+func encode83(value, length int) string {
+	result := make([]byte, length)
+	for i := 1; i <= length; i++ {
+		digit := (value / int(math.Pow(83, float64(length-i)))) % 83
+		result[i-1] = base83Characters[digit]
+	}
+	return string(result)
+}
+
+// srgbToLinear sRGB 分量转换为线性值
+func srgbToLinear(value uint8) float64 {
+	v := float64(value) / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// linearToSRGB 线性值转换为 sRGB 分量
+func linearToSRGB(value float64) int {
+	v := math.Max(0, math.Min(1, value))
+	if v <= 0.0031308 {
+		return int(v*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(v, 1/2.4)-0.055)*255 + 0.5)
+}
+
+// signPow 保留符号的幂运算
+func signPow(value, exp float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(value), exp), value)
+}
@@ -0,0 +1,112 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"testing"
+)
+
+// solidImage 生成纯色图片
+func solidImage(width, height int, c color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+	return img
+}
+
+func TestDominantColor(t *testing.T) {
+	mostlyRed := solidImage(4, 4, color.RGBA{R: 200, G: 10, B: 10, A: 255})
+	draw.Draw(mostlyRed, image.Rect(0, 0, 2, 2), image.NewUniform(color.RGBA{B: 255, A: 255}), image.Point{}, draw.Src)
+
+	tests := []struct {
+		name string
+		img  *image.RGBA
+		want string
+	}{
+		{"solid", solidImage(8, 8, color.RGBA{R: 0x12, G: 0x34, B: 0x56, A: 255}), "#123456"},
+		{"white", solidImage(3, 5, color.RGBA{R: 255, G: 255, B: 255, A: 255}), "#ffffff"},
+		{"majority", mostlyRed, "#c80a0a"},
+		{"empty", image.NewRGBA(image.Rect(0, 0, 0, 0)), "#000000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dominantColor(tt.img); got != tt.want {
+				t.Errorf("dominantColor() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlurHash(t *testing.T) {
+	tests := []struct {
+		name  string
+		img   *image.RGBA
+		flag  string // 分量数：4x3 为 L，3x4 为 T
+		color int
+	}{
+		{"landscape", solidImage(32, 24, color.RGBA{R: 0x12, G: 0x34, B: 0x56, A: 255}), "L", 0x123456},
+		{"portrait", solidImage(24, 32, color.RGBA{R: 0xff, G: 0x80, B: 0x00, A: 255}), "T", 0xff8000},
+		{"black", solidImage(16, 16, color.RGBA{A: 255}), "L", 0x000000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash := blurHash(tt.img)
+			// 大小标记 1 位、AC 最大值 1 位、DC 4 位，11 个 AC 分量各 2 位
+			if len(hash) != 28 {
+				t.Fatalf("blurHash() = %q, want 28 characters", hash)
+			}
+			if hash[:1] != tt.flag {
+				t.Errorf("blurHash() size flag = %q, want %q", hash[:1], tt.flag)
+			}
+			// 纯色图片的 DC 分量就是该颜色本身
+			if dc := encode83(tt.color, 4); hash[2:6] != dc {
+				t.Errorf("blurHash() DC = %q, want %q", hash[2:6], dc)
+			}
+		})
+	}
+}
+
+func TestEncode83(t *testing.T) {
+	tests := []struct {
+		value  int
+		length int
+		want   string
+	}{
+		{0, 1, "0"},
+		{21, 1, "L"},
+		{82, 1, "~"},
+		{83, 2, "10"},
+		{0xffffff, 4, "TSUA"},
+	}
+
+	for _, tt := range tests {
+		if got := encode83(tt.value, tt.length); got != tt.want {
+			t.Errorf("encode83(%d, %d) = %q, want %q", tt.value, tt.length, got, tt.want)
+		}
+	}
+}
+
+func TestAnalyzeImage(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, solidImage(300, 200, color.RGBA{R: 0x20, G: 0x40, B: 0x60, A: 255})); err != nil {
+		t.Fatal(err)
+	}
+
+	placeholder, err := AnalyzeImage(buf.Bytes(), "image/png")
+	if err != nil {
+		t.Fatalf("AnalyzeImage() error = %v", err)
+	}
+	if placeholder.Width != 300 || placeholder.Height != 200 {
+		t.Errorf("AnalyzeImage() size = %dx%d, want 300x200", placeholder.Width, placeholder.Height)
+	}
+	if placeholder.DominantColor != "#204060" {
+		t.Errorf("AnalyzeImage() DominantColor = %s, want #204060", placeholder.DominantColor)
+	}
+	if len(placeholder.BlurHash) != 28 || placeholder.BlurHash[2:6] != encode83(0x204060, 4) {
+		t.Errorf("AnalyzeImage() BlurHash = %q", placeholder.BlurHash)
+	}
+}
@@ -0,0 +1,195 @@
+package media
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// 水印位置
+const (
+	PositionTopLeft     = "top-left"
+	PositionTopRight    = "top-right"
+	PositionBottomLeft  = "bottom-left"
+	PositionBottomRight = "bottom-right"
+	PositionCenter      = "center"
+)
+
+// Watermark 文字或 PNG 图标水印
+type Watermark struct {
+	Text     string
+	Font     *opentype.Font
+	Logo     image.Image
+	Position string
+	Opacity  float64 // 不透明度（0-1）
+	Scale    float64 // 水印宽度占图片宽度的比例（0-1）
+}
+
+// NewWatermark 创建水印。logoPath 不为空时使用 PNG 图标，否则使用文字；
+// fontPath 为空时使用内置的 Go Bold 字体（不包含中文字形，中文水印需要指定字体文件）
+func NewWatermark(text, logoPath, fontPath, position string, opacity, scale float64) (*Watermark, error) {
+	switch position {
+	case PositionTopLeft, PositionTopRight, PositionBottomLeft, PositionBottomRight, PositionCenter:
+	default:
+		return nil, fmt.Errorf("无效的水印位置: %s", position)
+	}
+	if opacity <= 0 || opacity > 1 {
+		return nil, fmt.Errorf("无效的水印不透明度: %v", opacity)
+	}
+	if scale <= 0 || scale > 1 {
+		return nil, fmt.Errorf("无效的水印比例: %v", scale)
+	}
+
+	watermark := &Watermark{Text: text, Position: position, Opacity: opacity, Scale: scale}
+
+	if logoPath != "" {
+		data, err := os.ReadFile(logoPath)
+		if err != nil {
+			return nil, fmt.Errorf("读取水印图标失败: %v", err)
+		}
+		logo, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("解码水印图标失败: %v", err)
+		}
+		watermark.Logo = logo
+		return watermark, nil
+	}
+
+	if text == "" {
+		return nil, fmt.Errorf("水印文字和图标不能同时为空")
+	}
+	fontData := gobold.TTF
+	if fontPath != "" {
+		data, err := os.ReadFile(fontPath)
+		if err != nil {
+			return nil, fmt.Errorf("读取水印字体失败: %v", err)
+		}
+		fontData = data
+	}
+	parsed, err := opentype.Parse(fontData)
+	if err != nil {
+		return nil, fmt.Errorf("解析水印字体失败: %v", err)
+	}
+	watermark.Font = parsed
+	return watermark, nil
+}
+
+// Apply 在图片上绘制水印并重新编码（JPEG 先按 EXIF 方向摆正），返回新的内容和 MIME 类型
+func (w *Watermark) Apply(content []byte, mimeType string, jpegQuality int) ([]byte, string, error) {
+	img, err := decodeImage(content, mimeType)
+	if err != nil {
+		return nil, "", err
+	}
+	if mimeType == "image/jpeg" {
+		img = applyOrientation(img, jpegOrientation(content))
+	}
+
+	canvas := toRGBA(img)
+
+	var mark image.Image
+	if w.Logo != nil {
+		mark = w.renderLogo(canvas.Bounds().Dx())
+	} else {
+		mark, err = w.renderText(canvas.Bounds().Dx())
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	position := w.place(canvas.Bounds(), mark.Bounds())
+	mask := image.NewUniform(color.Alpha{A: uint8(w.Opacity * 255)})
+	draw.DrawMask(canvas, mark.Bounds().Add(position), mark, image.Point{}, mask, image.Point{}, draw.Over)
+
+	var buf bytes.Buffer
+	if mimeType == "image/png" || (mimeType == "image/webp" && !isOpaque(img)) {
+		err = png.Encode(&buf, canvas)
+		mimeType = "image/png"
+	} else {
+		err = jpeg.Encode(&buf, canvas, &jpeg.Options{Quality: quality(jpegQuality)})
+		mimeType = "image/jpeg"
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("编码图片失败: %v", err)
+	}
+	return buf.Bytes(), mimeType, nil
+}
+
+// renderLogo 按比例缩放图标
+func (w *Watermark) renderLogo(imageWidth int) image.Image {
+	bounds := w.Logo.Bounds()
+	width := max(1, int(float64(imageWidth)*w.Scale))
+	height := max(1, bounds.Dy()*width/bounds.Dx())
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), w.Logo, bounds, draw.Src, nil)
+	return dst
+}
+
+// renderText 绘制带阴影的白色文字，字号使文字宽度约为图片宽度的 Scale 倍
+func (w *Watermark) renderText(imageWidth int) (image.Image, error) {
+	const measureSize = 100
+	face, err := opentype.NewFace(w.Font, &opentype.FaceOptions{Size: measureSize, DPI: 72, Hinting: font.HintingNone})
+	if err != nil {
+		return nil, fmt.Errorf("创建字体失败: %v", err)
+	}
+	measured := font.MeasureString(face, w.Text).Ceil()
+	face.Close()
+	if measured <= 0 {
+		return nil, fmt.Errorf("水印文字无法绘制")
+	}
+
+	size := measureSize * float64(imageWidth) * w.Scale / float64(measured)
+	face, err = opentype.NewFace(w.Font, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingNone})
+	if err != nil {
+		return nil, fmt.Errorf("创建字体失败: %v", err)
+	}
+	defer face.Close()
+
+	metrics := face.Metrics()
+	shadow := max(1, int(size/20))
+	width := font.MeasureString(face, w.Text).Ceil() + shadow
+	height := (metrics.Ascent + metrics.Descent).Ceil() + shadow
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	drawer := &font.Drawer{Dst: dst, Face: face}
+	baseline := metrics.Ascent
+	drawer.Src = image.NewUniform(color.RGBA{A: 160})
+	drawer.Dot = fixed.Point26_6{X: fixed.I(shadow), Y: baseline + fixed.I(shadow)}
+	drawer.DrawString(w.Text)
+	drawer.Src = image.White
+	drawer.Dot = fixed.Point26_6{X: 0, Y: baseline}
+	drawer.DrawString(w.Text)
+
+	return dst, nil
+}
+
+// place 计算水印左上角的位置，边距为图片短边的 3%
+func (w *Watermark) place(canvas, mark image.Rectangle) image.Point {
+	margin := min(canvas.Dx(), canvas.Dy()) * 3 / 100
+	left := margin
+	top := margin
+	right := canvas.Dx() - mark.Dx() - margin
+	bottom := canvas.Dy() - mark.Dy() - margin
+
+	switch w.Position {
+	case PositionTopLeft:
+		return image.Pt(left, top)
+	case PositionTopRight:
+		return image.Pt(right, top)
+	case PositionBottomLeft:
+		return image.Pt(left, bottom)
+	case PositionCenter:
+		return image.Pt((canvas.Dx()-mark.Dx())/2, (canvas.Dy()-mark.Dy())/2)
+	default:
+		return image.Pt(right, bottom)
+	}
+}
@@ -0,0 +1,103 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWatermarkPlace(t *testing.T) {
+	// 1000x500 的图片边距为短边的 3%，即 15
+	canvas := image.Rect(0, 0, 1000, 500)
+	mark := image.Rect(0, 0, 200, 50)
+
+	tests := []struct {
+		position string
+		want     image.Point
+	}{
+		{PositionTopLeft, image.Pt(15, 15)},
+		{PositionTopRight, image.Pt(785, 15)},
+		{PositionBottomLeft, image.Pt(15, 435)},
+		{PositionBottomRight, image.Pt(785, 435)},
+		{PositionCenter, image.Pt(400, 225)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.position, func(t *testing.T) {
+			w := &Watermark{Position: tt.position}
+			if got := w.place(canvas, mark); got != tt.want {
+				t.Errorf("place() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewWatermark(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		position string
+		opacity  float64
+		scale    float64
+		wantErr  bool
+	}{
+		{"text", "moments", PositionBottomRight, 0.5, 0.2, false},
+		{"invalid position", "moments", "middle", 0.5, 0.2, true},
+		{"zero opacity", "moments", PositionBottomRight, 0, 0.2, true},
+		{"scale too large", "moments", PositionBottomRight, 0.5, 1.5, true},
+		{"empty text", "", PositionBottomRight, 0.5, 0.2, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewWatermark(tt.text, "", "", tt.position, tt.opacity, tt.scale)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewWatermark() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWatermarkApplyLogo(t *testing.T) {
+	logoPath := filepath.Join(t.TempDir(), "logo.png")
+	var logo bytes.Buffer
+	if err := png.Encode(&logo, solidImage(10, 10, color.RGBA{R: 255, G: 255, B: 255, A: 255})); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(logoPath, logo.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := NewWatermark("", logoPath, "", PositionBottomRight, 1, 0.2)
+	if err != nil {
+		t.Fatalf("NewWatermark() error = %v", err)
+	}
+
+	var photo bytes.Buffer
+	if err := png.Encode(&photo, solidImage(100, 100, color.RGBA{A: 255})); err != nil {
+		t.Fatal(err)
+	}
+	content, mimeType, err := w.Apply(photo.Bytes(), "image/png", 0)
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if mimeType != "image/png" {
+		t.Errorf("Apply() type = %s, want image/png", mimeType)
+	}
+
+	result, err := png.Decode(bytes.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 图标缩放到 20x20，放在距右下角 3 像素处
+	if r, _, _, _ := result.At(90, 90).RGBA(); r>>8 != 255 {
+		t.Errorf("pixel inside watermark = %d, want 255", r>>8)
+	}
+	if r, _, _, _ := result.At(10, 10).RGBA(); r != 0 {
+		t.Errorf("pixel outside watermark = %d, want 0", r>>8)
+	}
+}
@@ -0,0 +1,93 @@
+package metadata
+
+import (
+	"encoding/json"
+	"html"
+	"strings"
+
+	"moments-go/types"
+)
+
+// Version 当前元数据结构版本，字段只增不改
+const Version = 1
+
+const (
+	blockStart = "<!-- moments:metadata"
+	blockEnd   = "-->"
+)
+
+// Encode 将元数据编码为 HTML 注释块，GitHub 渲染时不可见。
+// json.Marshal 会转义 < 和 >，因此 JSON 中不会出现提前结束注释的 -->
+func Encode(meta *types.MomentMetadata) (string, error) {
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return blockStart + "\n" + string(data) + "\n" + blockEnd, nil
+}
+
+// Append 在正文末尾附加元数据块，meta 为 nil 或编码失败时原样返回
+func Append(body string, meta *types.MomentMetadata) string {
+	if meta == nil {
+		return body
+	}
+	block, err := Encode(meta)
+	if err != nil {
+		return body
+	}
+	return strings.TrimRight(body, "\n") + "\n\n" + block
+}
+
+// Parse 从正文中读取元数据块，返回元数据（没有或无法解析时为 nil）和去掉元数据块后的正文
+func Parse(body string) (*types.MomentMetadata, string) {
+	start := strings.Index(body, blockStart)
+	if start < 0 {
+		return nil, body
+	}
+	length := strings.Index(body[start:], blockEnd)
+	if length < 0 {
+		return nil, body
+	}
+
+	raw := body[start+len(blockStart) : start+length]
+	rest := strings.TrimSpace(body[:start] + body[start+length+len(blockEnd):])
+
+	var meta types.MomentMetadata
+	if err := json.Unmarshal([]byte(strings.TrimSpace(raw)), &meta); err != nil {
+		return nil, body
+	}
+	return &meta, rest
+}
+
+// Strip 去掉正文中的元数据块，用于显示
+func Strip(body string) string {
+	_, rest := Parse(body)
+	return rest
+}
+
+// MediaURLs 元数据中记录的媒体地址（包括封面缩略图）
+func MediaURLs(meta *types.MomentMetadata) []string {
+	urls := []string{}
+	for _, media := range meta.Media {
+		urls = append(urls, media.URL)
+		if media.ThumbnailURL != "" {
+			urls = append(urls, media.ThumbnailURL)
+		}
+	}
+	return urls
+}
+
+// RetainMedia 返回只保留正文中仍然引用的媒体的元数据副本，编辑动态删掉媒体后使用，meta 为 nil 时返回 nil
+func RetainMedia(meta *types.MomentMetadata, body string) *types.MomentMetadata {
+	if meta == nil {
+		return nil
+	}
+	retained := *meta
+	retained.Media = []types.MetadataMedia{}
+	for _, media := range meta.Media {
+		if strings.Contains(body, media.URL) || strings.Contains(body, html.EscapeString(media.URL)) {
+			retained.Media = append(retained.Media, media)
+		}
+	}
+	return &retained
+}
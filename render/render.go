@@ -0,0 +1,361 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"moments-go/config"
+	"moments-go/github"
+	"moments-go/metadata"
+	"moments-go/types"
+)
+
+// Post 模板可以访问的动态数据
+type Post struct {
+	Content   string    // 动态文字
+	Media     []Media   // 已上传的媒体文件
+	Labels    []string  // 发布时使用的标签
+	Author    Author    // 发布者
+	Time      time.Time // 发布时间（已转换到配置的时区）
+	Timestamp int64     // 发布时间的 Unix 时间戳
+	Location  *Location // 打卡位置，没有时为 nil
+	Source    Source    // 消息来源
+
+	ContentWarnings []string // 内容提醒，来自文字开头的 CW: 行
+}
+
+// Media 已上传的媒体文件
+type Media struct {
+	Name         string
+	URL          string
+	ThumbnailURL string
+	Type         string // MIME 类型
+	Kind         string // image / video / audio / sticker / file
+	Width        int
+	Height       int
+	Duration     int
+	Performer    string
+	Title        string
+	Alt          string
+	// 图片占位信息
+	DominantColor string
+	BlurHash      string
+	Embed         string // 默认的引用方式（Markdown 或 HTML）
+}
+
+// Author 发布者信息
+type Author struct {
+	ID       int64
+	Username string
+	Name     string
+}
+
+// Location 打卡位置
+type Location struct {
+	Latitude  float64
+	Longitude float64
+	Title     string
+	Address   string
+	MapURL    string // OpenStreetMap 地图链接
+	Embed     string // 默认的打卡信息
+}
+
+// Source 消息来源
+type Source struct {
+	Client    string // 客户端，目前固定为 telegram
+	Type      string // 消息类型：text / photo / video / location 等
+	ChatID    int64
+	MessageID int
+	Time      time.Time // 客户端发送消息的时间
+}
+
+const (
+	defaultTitleTemplate = `{{.Timestamp}}`
+	defaultBodyTemplate  = `{{if .ContentWarnings}}⚠️ 内容提醒：{{join .ContentWarnings "、"}}
+
+{{end}}{{.Content}}{{with .Location}}
+
+{{.Embed}}{{end}}{{range .Media}}
+{{.Embed}}{{end}}`
+)
+
+var (
+	titleTemplates = make(map[string]*template.Template) // 标签 -> 标题模板，空字符串为默认模板
+	bodyTemplates  = make(map[string]*template.Template) // 标签 -> 正文模板，空字符串为默认模板
+	location       = time.Local
+)
+
+// funcs 模板中可用的辅助函数
+var funcs = template.FuncMap{
+	"join":     strings.Join,
+	"duration": github.FormatDuration,
+	"truncate": truncate,
+	"hasLabel": hasLabel,
+}
+
+// Load 加载模板目录和时区。目录中的 title.tmpl、body.tmpl 覆盖默认模板，
+// title.<标签>.tmpl、body.<标签>.tmpl 为对应标签的专用模板；目录不存在时使用内置模板
+func Load(dir, timezone string) error {
+	if timezone != "" {
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			return fmt.Errorf("无效的时区 %s: %v", timezone, err)
+		}
+		location = loc
+	}
+
+	titles := map[string]*template.Template{"": template.Must(newTemplate("title").Parse(defaultTitleTemplate))}
+	bodies := map[string]*template.Template{"": template.Must(newTemplate("body").Parse(defaultBodyTemplate))}
+
+	if dir != "" {
+		paths, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+		if err != nil {
+			return fmt.Errorf("读取模板目录失败: %v", err)
+		}
+		for _, path := range paths {
+			kind, label := parseTemplateName(filepath.Base(path))
+			if kind == "" {
+				continue
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("读取模板 %s 失败: %v", path, err)
+			}
+			tmpl, err := newTemplate(filepath.Base(path)).Parse(string(data))
+			if err != nil {
+				return fmt.Errorf("解析模板 %s 失败: %v", path, err)
+			}
+			if kind == "title" {
+				titles[label] = tmpl
+			} else {
+				bodies[label] = tmpl
+			}
+		}
+	}
+
+	titleTemplates = titles
+	bodyTemplates = bodies
+	return nil
+}
+
+// newTemplate 创建带辅助函数的模板
+func newTemplate(name string) *template.Template {
+	return template.New(name).Funcs(funcs)
+}
+
+// parseTemplateName 解析模板文件名，返回模板类型（title / body）和标签
+func parseTemplateName(name string) (string, string) {
+	name = strings.TrimSuffix(name, ".tmpl")
+	kind, label, _ := strings.Cut(name, ".")
+	if kind != "title" && kind != "body" {
+		return "", ""
+	}
+	return kind, label
+}
+
+// Render 使用与标签匹配的模板生成 Issue 标题和正文
+func Render(post *Post) (string, string, error) {
+	var title, body bytes.Buffer
+	if err := selectTemplate(titleTemplates, post.Labels).Execute(&title, post); err != nil {
+		return "", "", fmt.Errorf("渲染标题模板失败: %v", err)
+	}
+	if err := selectTemplate(bodyTemplates, post.Labels).Execute(&body, post); err != nil {
+		return "", "", fmt.Errorf("渲染正文模板失败: %v", err)
+	}
+	result := strings.TrimSpace(body.String())
+	if config.Cfg.MetadataBlock {
+		result = metadata.Append(result, post.Metadata())
+	}
+	return strings.TrimSpace(title.String()), result, nil
+}
+
+// Metadata 生成动态的机器可读元数据
+func (post *Post) Metadata() *types.MomentMetadata {
+	meta := &types.MomentMetadata{
+		Version:         metadata.Version,
+		PublishedAt:     post.Time.Format(time.RFC3339),
+		Source:          &types.MetadataSource{Client: post.Source.Client, Type: post.Source.Type, ChatID: post.Source.ChatID, MessageID: post.Source.MessageID},
+		ContentWarnings: post.ContentWarnings,
+		Media:           []types.MetadataMedia{},
+	}
+	if !post.Source.Time.IsZero() {
+		meta.ClientTime = post.Source.Time.Format(time.RFC3339)
+	}
+	if post.Author != (Author{}) {
+		meta.Author = &types.MetadataAuthor{ID: post.Author.ID, Username: post.Author.Username, Name: post.Author.Name}
+	}
+	if post.Location != nil {
+		meta.Location = &types.Location{
+			Latitude:  post.Location.Latitude,
+			Longitude: post.Location.Longitude,
+			Title:     post.Location.Title,
+			Address:   post.Location.Address,
+		}
+	}
+	for _, media := range post.Media {
+		meta.Media = append(meta.Media, types.MetadataMedia{
+			URL:           media.URL,
+			ThumbnailURL:  media.ThumbnailURL,
+			Name:          media.Name,
+			Type:          media.Type,
+			Kind:          media.Kind,
+			Width:         media.Width,
+			Height:        media.Height,
+			Duration:      media.Duration,
+			DominantColor: media.DominantColor,
+			BlurHash:      media.BlurHash,
+		})
+	}
+	return meta
+}
+
+// selectTemplate 按标签顺序查找专用模板，没有时使用默认模板
+func selectTemplate(templates map[string]*template.Template, labels []string) *template.Template {
+	for _, label := range labels {
+		if tmpl, exists := templates[label]; exists && label != "" {
+			return tmpl
+		}
+	}
+	return templates[""]
+}
+
+// NewPost 根据待发布内容和已上传的媒体生成模板数据
+func NewPost(pending *types.PendingMedia, content string, labels []string, uploaded []github.UploadedMedia) *Post {
+	now := time.Now()
+	warnings, content := splitContentWarnings(content)
+	post := &Post{
+		ContentWarnings: warnings,
+		Content:         content,
+		Media:           []Media{},
+		Labels:          labels,
+		Time:            now.In(location),
+		Timestamp:       now.Unix(),
+		Source: Source{
+			Client: "telegram",
+			Type:   pending.Type,
+		},
+	}
+
+	if source := pending.Source; source != nil {
+		post.Author = Author{ID: source.AuthorID, Username: source.Username, Name: source.AuthorName}
+		post.Source.ChatID = source.ChatID
+		post.Source.MessageID = source.MessageID
+		if source.Date > 0 {
+			post.Source.Time = time.Unix(source.Date, 0).In(location)
+		}
+	}
+
+	if pending.Location != nil {
+		post.Location = &Location{
+			Latitude:  pending.Location.Latitude,
+			Longitude: pending.Location.Longitude,
+			Title:     pending.Location.Title,
+			Address:   pending.Location.Address,
+			MapURL:    MapURL(pending.Location),
+			Embed:     LocationEmbed(pending.Location),
+		}
+	}
+
+	for _, item := range uploaded {
+		file := item.File
+		post.Media = append(post.Media, Media{
+			Name:          file.Name,
+			URL:           item.URL,
+			ThumbnailURL:  item.ThumbnailURL,
+			Type:          file.Type,
+			Kind:          mediaKind(file),
+			Width:         file.Width,
+			Height:        file.Height,
+			Duration:      file.Duration,
+			Performer:     file.Performer,
+			Title:         file.Title,
+			Alt:           file.Alt,
+			DominantColor: file.DominantColor,
+			BlurHash:      file.BlurHash,
+			Embed:         github.RenderEmbed(file, item.URL, item.ThumbnailURL),
+		})
+	}
+
+	return post
+}
+
+// mediaKind 媒体文件的大类，方便模板区分显示方式
+func mediaKind(file *types.MediaFile) string {
+	switch {
+	case file.Type == github.TGSStickerType:
+		return "sticker"
+	case strings.HasPrefix(file.Type, "image/"):
+		return "image"
+	case strings.HasPrefix(file.Type, "video/"):
+		return "video"
+	case strings.HasPrefix(file.Type, "audio/"):
+		return "audio"
+	default:
+		return "file"
+	}
+}
+
+// MapURL 返回位置对应的 OpenStreetMap 地图链接
+func MapURL(location *types.Location) string {
+	return fmt.Sprintf("https://www.openstreetmap.org/?mlat=%.6f&mlon=%.6f#map=17/%.6f/%.6f",
+		location.Latitude, location.Longitude, location.Latitude, location.Longitude)
+}
+
+// LocationEmbed 生成动态内容中的打卡信息，附带地图链接
+func LocationEmbed(location *types.Location) string {
+	block := "📍 "
+	if location.Title != "" {
+		block += fmt.Sprintf("**%s**", location.Title)
+		if location.Address != "" {
+			block += " · " + location.Address
+		}
+		block += "\n"
+	}
+	return block + fmt.Sprintf("[%.6f, %.6f](%s)", location.Latitude, location.Longitude, MapURL(location))
+}
+
+// truncate 按字符截断文本
+func truncate(maxRunes int, s string) string {
+	runes := []rune(s)
+	if len(runes) <= maxRunes {
+		return s
+	}
+	return string(runes[:maxRunes]) + "..."
+}
+
+// hasLabel 判断标签列表中是否包含指定标签
+func hasLabel(labels []string, label string) bool {
+	for _, l := range labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+// splitContentWarnings 提取文字开头以 CW: 或 内容提醒: 开头的行作为内容提醒
+func splitContentWarnings(content string) ([]string, string) {
+	var warnings []string
+	lines := strings.Split(content, "\n")
+	for len(lines) > 0 {
+		line := strings.TrimSpace(lines[0])
+		warning := ""
+		for _, prefix := range []string{"CW:", "CW：", "cw:", "cw：", "内容提醒:", "内容提醒："} {
+			if strings.HasPrefix(line, prefix) {
+				warning = strings.TrimSpace(strings.TrimPrefix(line, prefix))
+				break
+			}
+		}
+		if warning == "" {
+			break
+		}
+		warnings = append(warnings, warning)
+		lines = lines[1:]
+	}
+	return warnings, strings.TrimSpace(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"moments-go/github"
+	"moments-go/types"
+)
+
+// publishMutex 发布动态时从上传文件到创建 Issue 期间持有读锁，删除未引用文件时持有写锁，
+// 避免已上传但尚未被动态引用的文件被当作未引用文件删除
+var publishMutex sync.RWMutex
+
+// BeginPublish 开始发布动态，动态创建完成或失败后调用返回的函数
+func BeginPublish() func() {
+	publishMutex.RLock()
+	return publishMutex.RUnlock
+}
+
+// FindOrphans 列出文件仓库中没有被任何动态（包括回收站中的动态）引用的媒体文件，
+// 媒体文件指 moments/ 目录下或符合 MEDIA_PATH_TEMPLATE 的文件。
+// 本地哈希索引中记录的文件会在重新发布时复用，不算未引用；动态中的地址可能是任意格式
+// （如改过 MEDIA_URL_TEMPLATE 之前的 CDN 地址），正文中出现文件路径（原样或转义后）的文件都算被引用
+func FindOrphans() ([]github.RepoFile, error) {
+	files, err := github.ListRepoFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	referenced := indexedRepoPaths()
+	var bodies strings.Builder
+	for _, state := range []string{"open", "closed"} {
+		issues, err := github.ListAllIssues(state)
+		if err != nil {
+			return nil, fmt.Errorf("获取动态失败: %v", err)
+		}
+		for _, issue := range issues {
+			for _, path := range github.ExtractMediaPaths(issue.Body) {
+				referenced[path] = true
+			}
+			bodies.WriteString(issue.Body)
+			bodies.WriteString("\n")
+		}
+	}
+
+	body := bodies.String()
+	var orphans []github.RepoFile
+	for _, file := range files {
+		if github.IsMediaPath(file.Path) && !referenced[file.Path] && !mentionsPath(body, file.Path) {
+			orphans = append(orphans, file)
+		}
+	}
+	return orphans, nil
+}
+
+// mentionsPath 检查内容中是否出现文件路径，不论地址的前缀是什么格式
+func mentionsPath(body, path string) bool {
+	return strings.Contains(body, "/"+path) || strings.Contains(body, objectURL("", path))
+}
+
+// DeleteOrphans 重新检查后在一次提交中删除确认过的未引用文件，检查和删除期间不会发布新的动态，
+// 已被新动态引用的文件会被跳过，返回实际删除的文件
+func DeleteOrphans(paths []string) ([]github.RepoFile, error) {
+	publishMutex.Lock()
+	defer publishMutex.Unlock()
+	repoMutex.RLock()
+	defer repoMutex.RUnlock()
+
+	confirmed := make(map[string]bool)
+	for _, path := range paths {
+		confirmed[path] = true
+	}
+
+	orphans, err := FindOrphans()
+	if err != nil {
+		return nil, err
+	}
+
+	var deleted []github.RepoFile
+	var deletePaths []string
+	for _, orphan := range orphans {
+		if confirmed[orphan.Path] {
+			deleted = append(deleted, orphan)
+			deletePaths = append(deletePaths, orphan.Path)
+		}
+	}
+
+	if err := github.DeleteFilesFromGitHub(deletePaths); err != nil {
+		return nil, err
+	}
+	for _, path := range deletePaths {
+		forgetFile(types.UploadedFile{Path: path, Store: "github"})
+	}
+	return deleted, nil
+}
+
+// FormatOrphans 生成未引用文件的报告，最多列出 limit 个文件（0 表示全部列出）
+func FormatOrphans(orphans []github.RepoFile, limit int) string {
+	if len(orphans) == 0 {
+		return "没有未被引用的文件"
+	}
+
+	var total int64
+	for _, orphan := range orphans {
+		total += orphan.Size
+	}
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("共 %d 个未被引用的文件，合计 %s：\n", len(orphans), FormatBytes(total)))
+	for i, orphan := range orphans {
+		if limit > 0 && i >= limit {
+			builder.WriteString(fmt.Sprintf("…… 还有 %d 个文件\n", len(orphans)-limit))
+			break
+		}
+		builder.WriteString(fmt.Sprintf("%s（%s）\n", orphan.Path, FormatBytes(orphan.Size)))
+	}
+	return builder.String()
+}
+
+// FormatBytes 将字节数格式化为可读的大小
+func FormatBytes(size int64) string {
+	switch {
+	case size >= 1024*1024*1024:
+		return fmt.Sprintf("%.1fGB", float64(size)/1024/1024/1024)
+	case size >= 1024*1024:
+		return fmt.Sprintf("%.1fMB", float64(size)/1024/1024)
+	case size >= 1024:
+		return fmt.Sprintf("%.1fKB", float64(size)/1024)
+	default:
+		return fmt.Sprintf("%dB", size)
+	}
+}
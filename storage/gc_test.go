@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+
+	"moments-go/config"
+	"moments-go/types"
+)
+
+func TestMentionsPath(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		path string
+		want bool
+	}{
+		{"raw", "![图片](https://raw.githubusercontent.com/alice/files/main/2024/01/abc.jpg)", "2024/01/abc.jpg", true},
+		{"old cdn", `<img src="https://cdn.example.com/files@main/2024/01/abc.jpg">`, "2024/01/abc.jpg", true},
+		{"escaped", "https://img.example.com/moments/abc/My%20Photo.jpg", "moments/abc/My Photo.jpg", true},
+		{"other file", "https://img.example.com/2024/01/abcd.jpg", "2024/01/abc.jpg", false},
+		{"not referenced", "今天天气不错", "2024/01/abc.jpg", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mentionsPath(tt.body, tt.path); got != tt.want {
+				t.Errorf("mentionsPath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndexedRepoPaths(t *testing.T) {
+	savedIndex, savedPath := index, hashIndexPath
+	current, previous := config.GetFileRepo(), config.PreviousFileRepos
+	t.Cleanup(func() {
+		index, hashIndexPath = savedIndex, savedPath
+		config.SetFileRepos(current, previous)
+	})
+
+	hashIndexPath = ""
+	config.SetFileRepos("files-2024", []string{"files"})
+	index = hashIndexFile{Files: map[string]types.UploadedFile{
+		"a": {Path: "moments/a/photo.jpg"},
+		"b": {Path: "moments/b/photo.jpg", Repo: "files-2024"},
+		"c": {Path: "moments/c/photo.jpg", Repo: "files"},
+		"d": {Path: "d_movie.mp4", Release: "media"},
+		"e": {Path: "moments/e/photo.jpg", Store: "s3"},
+	}}
+
+	want := map[string]bool{"moments/a/photo.jpg": true, "moments/b/photo.jpg": true}
+	if got := indexedRepoPaths(); !reflect.DeepEqual(got, want) {
+		t.Errorf("indexedRepoPaths() = %v, want %v", got, want)
+	}
+}
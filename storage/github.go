@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"moments-go/github"
+	"moments-go/types"
+)
+
+// githubStore 文件仓库存储：小文件提交到 GITHUB_FILE_REPO，大视频上传为 Release 附件
+type githubStore struct{}
+
+func (githubStore) Name() string {
+	return "github"
+}
+
+func (githubStore) Upload(file *types.MediaFile, timestamp string) (*types.UploadedFile, error) {
+	return github.StageFile(file, timestamp)
+}
+
+// Commit Git Data API 上传的文件在一次提交中添加到文件仓库
+func (githubStore) Commit(files []types.UploadedFile) ([]types.UploadedFile, error) {
+	return github.CommitFiles(files)
+}
+
+func (githubStore) Delete(file types.UploadedFile) error {
+	return github.DeleteUploadedFile(file)
+}
+
+func (githubStore) Find(path string) (*types.UploadedFile, error) {
+	return github.FindRepoFile(path)
+}
+
+func (githubStore) Extract(body string) []types.UploadedFile {
+	return github.ExtractRepoFiles(body)
+}
@@ -0,0 +1,320 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"moments-go/config"
+	"moments-go/types"
+)
+
+// hashIndexFile 本地哈希索引文件格式
+type hashIndexFile struct {
+	Files   map[string]types.UploadedFile `json:"files"`   // 内容哈希 -> 已上传的文件
+	Sources map[string]*sourceEntry       `json:"sources"` // 来源标识 -> 处理后的媒体
+}
+
+// sourceEntry 来源文件处理并上传后的媒体，下载前命中时直接复用
+type sourceEntry struct {
+	Hash          string       `json:"hash"`
+	Name          string       `json:"name"`
+	Type          string       `json:"type"`
+	Width         int          `json:"width,omitempty"`
+	Height        int          `json:"height,omitempty"`
+	DominantColor string       `json:"dominant_color,omitempty"`
+	BlurHash      string       `json:"blurhash,omitempty"`
+	Thumbnail     *sourceEntry `json:"thumbnail,omitempty"`
+}
+
+var (
+	index = hashIndexFile{
+		Files:   make(map[string]types.UploadedFile),
+		Sources: make(map[string]*sourceEntry),
+	}
+	hashIndexPath string
+	indexMutex    sync.Mutex
+	// indexInfo 最近一次读取或写入的索引文件，文件被其他进程（如命令行 migrate）替换后重新加载
+	indexInfo os.FileInfo
+)
+
+// openIndex 加载本地哈希索引，文件不存在时使用空索引
+func openIndex(dataDir string) error {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return fmt.Errorf("创建数据目录失败: %v", err)
+	}
+
+	indexMutex.Lock()
+	defer indexMutex.Unlock()
+
+	hashIndexPath = filepath.Join(dataDir, "media.json")
+	return loadIndex()
+}
+
+// loadIndex 磁盘上的索引文件在上次读写之后发生变化时重新加载，以磁盘上的内容为准，调用方需持有锁
+func loadIndex() error {
+	if hashIndexPath == "" {
+		return nil
+	}
+
+	info, err := os.Stat(hashIndexPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取媒体索引失败: %v", err)
+	}
+	if indexInfo != nil && os.SameFile(indexInfo, info) && indexInfo.ModTime().Equal(info.ModTime()) && indexInfo.Size() == info.Size() {
+		return nil
+	}
+
+	data, err := os.ReadFile(hashIndexPath)
+	if err != nil {
+		return fmt.Errorf("读取媒体索引失败: %v", err)
+	}
+	var file hashIndexFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("解析媒体索引失败: %v", err)
+	}
+	if file.Files == nil {
+		file.Files = make(map[string]types.UploadedFile)
+	}
+	if file.Sources == nil {
+		file.Sources = make(map[string]*sourceEntry)
+	}
+	index = file
+	indexInfo = info
+	return nil
+}
+
+// reloadIndex 使用索引前重新加载其他进程写入的内容，失败时继续使用内存中的索引，调用方需持有锁
+func reloadIndex() {
+	if err := loadIndex(); err != nil {
+		log.Printf("重新加载媒体索引失败: %v", err)
+	}
+}
+
+// saveIndex 将索引写入磁盘，调用方需持有锁
+func saveIndex() {
+	if hashIndexPath == "" {
+		return
+	}
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		log.Printf("序列化媒体索引失败: %v", err)
+		return
+	}
+
+	// 先写临时文件再重命名，避免写入中断导致索引损坏
+	tmpPath := hashIndexPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		log.Printf("写入媒体索引失败: %v", err)
+		return
+	}
+	if err := os.Rename(tmpPath, hashIndexPath); err != nil {
+		log.Printf("写入媒体索引失败: %v", err)
+		return
+	}
+	if info, err := os.Stat(hashIndexPath); err == nil {
+		indexInfo = info
+	}
+}
+
+// ownerFileName 保存运行中的机器人进程号的文件，命令行 gc 据此判断机器人是否在运行
+const ownerFileName = "bot.pid"
+
+// ClaimIndex 记录机器人的进程号。机器人运行期间以它内存中的索引和发布状态为准，
+// 命令行 gc 无法与机器人的发布过程互斥，会拒绝运行
+func ClaimIndex() error {
+	path := filepath.Join(config.Cfg.DataDir, ownerFileName)
+	if err := os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())), 0644); err != nil {
+		return fmt.Errorf("写入进程号失败: %v", err)
+	}
+	return nil
+}
+
+// RunningBot 返回正在运行的机器人的进程号，没有运行时返回 0
+func RunningBot() int {
+	data, err := os.ReadFile(filepath.Join(config.Cfg.DataDir, ownerFileName))
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 || pid == os.Getpid() {
+		return 0
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return 0
+	}
+	// 信号 0 只检查进程是否存在
+	if err := process.Signal(syscall.Signal(0)); err != nil {
+		return 0
+	}
+	return pid
+}
+
+// hashContent 计算文件内容的 SHA-256
+func hashContent(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// hashReader 以流的形式计算文件内容的 SHA-256，不把文件读入内存
+func hashReader(file *types.MediaFile) (string, error) {
+	reader, _, err := file.Reader()
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, reader); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// lookupHash 查找已上传的相同内容的文件
+func lookupHash(hash string) (types.UploadedFile, bool) {
+	indexMutex.Lock()
+	defer indexMutex.Unlock()
+	reloadIndex()
+	uploaded, exists := index.Files[hash]
+	return uploaded, exists
+}
+
+// indexedRepoPaths 索引中记录的、保存在当前文件仓库中的文件路径（不包括 Release 附件）
+func indexedRepoPaths() map[string]bool {
+	indexMutex.Lock()
+	defer indexMutex.Unlock()
+	reloadIndex()
+
+	paths := make(map[string]bool)
+	for _, uploaded := range index.Files {
+		if storeName(uploaded) == "github" && uploaded.Release == "" && fileRepo(uploaded) == config.GetFileRepo() {
+			paths[uploaded.Path] = true
+		}
+	}
+	return paths
+}
+
+// recordUploads 记录上传后的文件及其来源，上传失败的文件（Path 为空）不记录，
+// 已上传成功的文件在重新发布时可以直接复用
+func recordUploads(mediaFiles []*types.MediaFile, files []*types.MediaFile, uploaded []types.UploadedFile) {
+	indexMutex.Lock()
+	defer indexMutex.Unlock()
+	reloadIndex()
+
+	for i, file := range files {
+		if file.Hash != "" && uploaded[i].Path != "" {
+			index.Files[file.Hash] = uploaded[i]
+		}
+	}
+	for _, file := range mediaFiles {
+		if file.SourceID == "" || !recorded(file) {
+			continue
+		}
+		entry := newSourceEntry(file)
+		if file.Thumbnail != nil {
+			if !recorded(file.Thumbnail) {
+				continue
+			}
+			entry.Thumbnail = newSourceEntry(file.Thumbnail)
+		}
+		index.Sources[file.SourceID] = entry
+	}
+	saveIndex()
+}
+
+// recorded 文件是否已记录在索引中，调用方需持有锁
+func recorded(file *types.MediaFile) bool {
+	if file.Hash == "" {
+		return false
+	}
+	_, exists := index.Files[file.Hash]
+	return exists
+}
+
+func newSourceEntry(file *types.MediaFile) *sourceEntry {
+	return &sourceEntry{
+		Hash:          file.Hash,
+		Name:          file.Name,
+		Type:          file.Type,
+		Width:         file.Width,
+		Height:        file.Height,
+		DominantColor: file.DominantColor,
+		BlurHash:      file.BlurHash,
+	}
+}
+
+// FindSource 查找同一来源文件此前处理并上传的媒体，命中时无需下载。
+// 返回的媒体没有内容，上传时会按哈希复用已有地址
+func FindSource(sourceID string) *types.MediaFile {
+	indexMutex.Lock()
+	defer indexMutex.Unlock()
+	reloadIndex()
+
+	entry, exists := index.Sources[sourceID]
+	if !exists {
+		return nil
+	}
+	file := entry.mediaFile()
+	if _, exists := index.Files[entry.Hash]; !exists {
+		return nil
+	}
+	if entry.Thumbnail != nil {
+		if _, exists := index.Files[entry.Thumbnail.Hash]; !exists {
+			return nil
+		}
+		file.Thumbnail = entry.Thumbnail.mediaFile()
+	}
+	file.SourceID = sourceID
+	return file
+}
+
+func (e *sourceEntry) mediaFile() *types.MediaFile {
+	return &types.MediaFile{
+		Name:          e.Name,
+		Type:          e.Type,
+		Width:         e.Width,
+		Height:        e.Height,
+		DominantColor: e.DominantColor,
+		BlurHash:      e.BlurHash,
+		Hash:          e.Hash,
+	}
+}
+
+// forgetFile 文件删除后从索引中移除，并移除引用该文件的来源
+func forgetFile(file types.UploadedFile) {
+	indexMutex.Lock()
+	defer indexMutex.Unlock()
+	reloadIndex()
+
+	removed := make(map[string]bool)
+	for hash, uploaded := range index.Files {
+		if sameFile(uploaded, file) {
+			delete(index.Files, hash)
+			removed[hash] = true
+		}
+	}
+	if len(removed) == 0 {
+		return
+	}
+	for id, entry := range index.Sources {
+		if removed[entry.Hash] || (entry.Thumbnail != nil && removed[entry.Thumbnail.Hash]) {
+			delete(index.Sources, id)
+		}
+	}
+	saveIndex()
+}
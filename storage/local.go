@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"moments-go/config"
+	"moments-go/types"
+)
+
+// localStore 本地存储：文件保存在 LOCAL_STORAGE_DIR，由机器人自带的 HTTP 服务提供访问
+type localStore struct {
+	dir       string
+	publicURL string
+}
+
+func newLocalStore() (*localStore, error) {
+	cfg := config.Cfg
+	if cfg.LocalPublicURL == "" {
+		return nil, fmt.Errorf("使用本地存储需要设置 LOCAL_PUBLIC_URL")
+	}
+	if err := os.MkdirAll(cfg.LocalStorageDir, 0755); err != nil {
+		return nil, fmt.Errorf("创建本地存储目录失败: %v", err)
+	}
+	return &localStore{dir: cfg.LocalStorageDir, publicURL: cfg.LocalPublicURL}, nil
+}
+
+func (s *localStore) Name() string {
+	return "local"
+}
+
+func (s *localStore) Upload(file *types.MediaFile, timestamp string) (*types.UploadedFile, error) {
+	key := objectKey(file, timestamp)
+	if err := s.writeFile(key, file); err != nil {
+		return nil, fmt.Errorf("保存文件 %s 失败: %v", file.Name, err)
+	}
+	return &types.UploadedFile{Path: key, URL: objectURL(s.publicURL, key)}, nil
+}
+
+func (s *localStore) Commit(files []types.UploadedFile) ([]types.UploadedFile, error) {
+	return files, nil
+}
+
+func (s *localStore) Delete(file types.UploadedFile) error {
+	if err := os.Remove(s.path(file.Path)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("删除文件 %s 失败: %v", file.Path, err)
+	}
+	return nil
+}
+
+func (s *localStore) Find(key string) (*types.UploadedFile, error) {
+	if _, err := os.Stat(s.path(key)); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &types.UploadedFile{Path: key, URL: objectURL(s.publicURL, key)}, nil
+}
+
+func (s *localStore) Extract(body string) []types.UploadedFile {
+	return extractObjects(body, s.publicURL, s.Name())
+}
+
+// path 存储路径对应的本地文件路径，不允许跳出存储目录
+func (s *localStore) path(key string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(filepath.Clean("/"+key)))
+}
+
+// writeFile 先写入临时文件再重命名，避免 HTTP 服务读到不完整的文件
+func (s *localStore) writeFile(key string, file *types.MediaFile) error {
+	reader, _, err := file.Reader()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	target := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, reader); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
+
+// StartServer 使用本地存储时启动 HTTP 服务，在 LOCAL_PUBLIC_URL 的路径下提供媒体文件
+func StartServer() {
+	store, ok := stores["local"].(*localStore)
+	if !ok {
+		return
+	}
+
+	prefix := "/"
+	if parsed, err := url.Parse(store.publicURL); err == nil && parsed.Path != "" {
+		prefix = strings.TrimSuffix(parsed.Path, "/") + "/"
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(prefix, http.StripPrefix(prefix, noDirListing(http.FileServer(http.Dir(store.dir)))))
+
+	go func() {
+		log.Printf("本地媒体文件服务已启动: %s%s", config.Cfg.HTTPListen, prefix)
+		if err := http.ListenAndServe(config.Cfg.HTTPListen, mux); err != nil {
+			log.Printf("本地媒体文件服务异常退出: %v", err)
+		}
+	}()
+}
+
+// noDirListing 不列出目录内容
+func noDirListing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "" || strings.HasSuffix(r.URL.Path, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
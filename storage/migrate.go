@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+
+	"moments-go/github"
+	"moments-go/store"
+)
+
+// URLMigration 一条动态的地址改写
+type URLMigration struct {
+	IssueNumber int
+	Body        string // 改写后的正文
+	Count       int    // 改写的地址数
+}
+
+// PlanURLMigration 找出引用了文件仓库旧地址的动态（包括回收站中的动态），计算按当前 MEDIA_URL_TEMPLATE 改写后的正文
+func PlanURLMigration() ([]URLMigration, error) {
+	var migrations []URLMigration
+	for _, state := range []string{"open", "closed"} {
+		issues, err := github.ListAllIssues(state)
+		if err != nil {
+			return nil, fmt.Errorf("获取动态失败: %v", err)
+		}
+		for _, issue := range issues {
+			body, count, err := github.RewriteRepoURLs(issue.Body)
+			if err != nil {
+				return nil, err
+			}
+			if count > 0 {
+				migrations = append(migrations, URLMigration{IssueNumber: issue.Number, Body: body, Count: count})
+			}
+		}
+	}
+	return migrations, nil
+}
+
+// ApplyURLMigration 更新动态正文和本地索引，并改写哈希索引中的地址，返回已更新的动态数。
+// 部分动态更新失败时同样改写哈希索引，之后上传的相同文件使用新地址
+func ApplyURLMigration(migrations []URLMigration) (int, error) {
+	updated := 0
+	var updateErr error
+	for _, migration := range migrations {
+		issue, err := github.UpdateGitHubIssueBody(migration.IssueNumber, migration.Body)
+		if err != nil {
+			updateErr = fmt.Errorf("更新动态 #%d 失败: %v", migration.IssueNumber, err)
+			break
+		}
+		store.PutIssue(issue)
+		updated++
+	}
+
+	if err := rewriteIndexURLs(); err != nil {
+		return updated, err
+	}
+	return updated, updateErr
+}
+
+// rewriteIndexURLs 将哈希索引中文件仓库的地址改写为当前 MEDIA_URL_TEMPLATE 生成的地址
+func rewriteIndexURLs() error {
+	indexMutex.Lock()
+	defer indexMutex.Unlock()
+	reloadIndex()
+
+	changed := false
+	for hash, uploaded := range index.Files {
+		if storeName(uploaded) != "github" || uploaded.Release != "" {
+			continue
+		}
+		rewritten, count, err := github.RewriteRepoURLs(uploaded.URL)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			uploaded.URL = rewritten
+			index.Files[hash] = uploaded
+			changed = true
+		}
+	}
+	if changed {
+		saveIndex()
+	}
+	return nil
+}
+
+// FormatURLMigration 生成地址改写的报告
+func FormatURLMigration(migrations []URLMigration) string {
+	if len(migrations) == 0 {
+		return "所有动态都已使用当前的地址"
+	}
+
+	total := 0
+	var numbers []string
+	for _, migration := range migrations {
+		total += migration.Count
+		numbers = append(numbers, fmt.Sprintf("#%d（%d）", migration.IssueNumber, migration.Count))
+	}
+	return fmt.Sprintf("共 %d 条动态的 %d 个地址需要改写：\n%s\n", len(migrations), total, strings.Join(numbers, "、"))
+}
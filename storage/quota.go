@@ -0,0 +1,253 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"moments-go/config"
+	"moments-go/github"
+)
+
+// repoState 文件仓库切换记录，保存在 DATA_DIR/file-repos.json，重启后继续使用切换后的仓库
+type repoState struct {
+	Configured string             `json:"configured"` // 切换时 GITHUB_FILE_REPO 的值，配置修改后不再沿用切换记录
+	Current    string             `json:"current"`
+	Previous   []string           `json:"previous"`
+	Warned     map[string][]int64 `json:"warned"` // 仓库名 -> 已提醒过的阈值（MB）
+	// 仓库名 -> 自动切换失败并已通知过的切换阈值（MB），之后的检查仍会重试但不再通知
+	RolloverFailed map[string][]int64 `json:"rollover_failed,omitempty"`
+}
+
+// QuotaStatus 一次文件仓库大小检查的结果
+type QuotaStatus struct {
+	Repo       string
+	Size       int64   // 字节
+	Warnings   []int64 // 本次新超过的提醒阈值（MB）
+	RolledOver string  // 自动切换到的新仓库，未切换时为空
+	// 自动切换第一次失败，需要通知用户；同一仓库和切换阈值再次失败时为 false
+	RolloverFailed bool
+}
+
+var (
+	state      repoState
+	statePath  string
+	stateMutex sync.Mutex
+
+	// 上传和删除期间持有读锁，切换文件仓库时持有写锁，避免一次提交跨越两个仓库
+	repoMutex sync.RWMutex
+)
+
+// rolloverSuffix 匹配自动切换生成的仓库名后缀，如 -2026、-2026-2
+var rolloverSuffix = regexp.MustCompile(`-\d{4}(-\d+)?$`)
+
+// openRepoState 加载文件仓库切换记录，GITHUB_FILE_REPO 未修改时沿用切换后的仓库
+func openRepoState(dataDir string) error {
+	stateMutex.Lock()
+	defer stateMutex.Unlock()
+
+	statePath = filepath.Join(dataDir, "file-repos.json")
+	data, err := os.ReadFile(statePath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("读取文件仓库记录失败: %v", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &state); err != nil {
+			return fmt.Errorf("解析文件仓库记录失败: %v", err)
+		}
+	}
+	if state.Warned == nil {
+		state.Warned = make(map[string][]int64)
+	}
+	if state.RolloverFailed == nil {
+		state.RolloverFailed = make(map[string][]int64)
+	}
+
+	configured := config.GetFileRepo()
+	if state.Configured != configured {
+		// 首次运行或手动修改了 GITHUB_FILE_REPO：使用配置的仓库，之前的仓库仍然保留以便识别旧地址
+		previous := state.Previous
+		if state.Current != "" && state.Current != configured {
+			previous = append([]string{state.Current}, previous...)
+		}
+		state.Configured = configured
+		state.Current = configured
+		state.Previous = removeRepo(previous, configured)
+	}
+
+	config.SetFileRepos(state.Current, state.Previous)
+	return saveRepoState()
+}
+
+// saveRepoState 将切换记录写入磁盘，调用方需持有锁
+func saveRepoState() error {
+	if statePath == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化文件仓库记录失败: %v", err)
+	}
+
+	// 先写临时文件再重命名，避免写入中断导致记录损坏
+	tmpPath := statePath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("写入文件仓库记录失败: %v", err)
+	}
+	return os.Rename(tmpPath, statePath)
+}
+
+// UsesFileRepo 是否有媒体类型保存在 GitHub 文件仓库中
+func UsesFileRepo() bool {
+	for _, mimeType := range []string{"image/", "video/", "audio/", ""} {
+		if ForType(mimeType).Name() == "github" {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckFileRepo 检查当前文件仓库的大小，返回新超过的提醒阈值；
+// 设置了 FILE_REPO_ROLLOVER_MB 且超过时自动创建新仓库并切换
+func CheckFileRepo() (*QuotaStatus, error) {
+	size, err := github.GetFileRepoSize()
+	if err != nil {
+		return nil, err
+	}
+
+	stateMutex.Lock()
+	defer stateMutex.Unlock()
+
+	status := &QuotaStatus{Repo: state.Current, Size: size}
+	thresholds := append([]int64(nil), config.Cfg.FileRepoWarnMB...)
+	sort.Slice(thresholds, func(i, j int) bool { return thresholds[i] < thresholds[j] })
+	for _, threshold := range thresholds {
+		if size < threshold*1024*1024 || containsThreshold(state.Warned[state.Current], threshold) {
+			continue
+		}
+		status.Warnings = append(status.Warnings, threshold)
+		state.Warned[state.Current] = append(state.Warned[state.Current], threshold)
+	}
+
+	if config.Cfg.FileRepoRolloverMB > 0 && size >= config.Cfg.FileRepoRolloverMB*1024*1024 {
+		repo, err := rollover()
+		if err != nil {
+			threshold := config.Cfg.FileRepoRolloverMB
+			if !containsThreshold(state.RolloverFailed[state.Current], threshold) {
+				status.RolloverFailed = true
+				state.RolloverFailed[state.Current] = append(state.RolloverFailed[state.Current], threshold)
+			}
+			saveRepoState()
+			return status, fmt.Errorf("切换文件仓库失败: %v", err)
+		}
+		status.RolledOver = repo
+	}
+
+	return status, saveRepoState()
+}
+
+// rollover 创建新的文件仓库并切换，旧仓库保留，其中文件的地址继续有效。调用方需持有 stateMutex
+func rollover() (string, error) {
+	name, err := nextRepoName()
+	if err != nil {
+		return "", err
+	}
+	if err := github.CreateFileRepo(name); err != nil {
+		return "", err
+	}
+
+	// 等待正在进行的上传完成，之后的上传使用新仓库
+	repoMutex.Lock()
+	defer repoMutex.Unlock()
+
+	old := state.Current
+	stampRepo(old)
+	state.Previous = append([]string{old}, removeRepo(state.Previous, old)...)
+	state.Current = name
+	config.SetFileRepos(state.Current, state.Previous)
+	return name, nil
+}
+
+// nextRepoName 新仓库的名称：<原仓库名>-<年份>，已存在时追加序号
+func nextRepoName() (string, error) {
+	base := rolloverSuffix.ReplaceAllString(state.Configured, "")
+	year := time.Now().Format("2006")
+	for i := 1; ; i++ {
+		name := fmt.Sprintf("%s-%s", base, year)
+		if i > 1 {
+			name = fmt.Sprintf("%s-%s-%d", base, year, i)
+		}
+		if name == state.Current || containsRepo(state.Previous, name) {
+			continue
+		}
+		exists, err := github.RepoExists(name)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return name, nil
+		}
+	}
+}
+
+// stampRepo 切换前为没有记录仓库的文件补上旧仓库名，避免之后被当作新仓库中的文件
+func stampRepo(repo string) {
+	indexMutex.Lock()
+	reloadIndex()
+	changed := false
+	for hash, uploaded := range index.Files {
+		if storeName(uploaded) == "github" && uploaded.Repo == "" {
+			uploaded.Repo = repo
+			index.Files[hash] = uploaded
+			changed = true
+		}
+	}
+	if changed {
+		saveIndex()
+	}
+	indexMutex.Unlock()
+
+	config.UndoMutex.Lock()
+	for _, record := range config.UndoRecords {
+		for i, file := range record.Files {
+			if storeName(file) == "github" && file.Repo == "" {
+				record.Files[i].Repo = repo
+			}
+		}
+	}
+	config.UndoMutex.Unlock()
+}
+
+func containsThreshold(thresholds []int64, threshold int64) bool {
+	for _, item := range thresholds {
+		if item == threshold {
+			return true
+		}
+	}
+	return false
+}
+
+func containsRepo(repos []string, repo string) bool {
+	for _, item := range repos {
+		if item == repo {
+			return true
+		}
+	}
+	return false
+}
+
+func removeRepo(repos []string, repo string) []string {
+	var result []string
+	for _, item := range repos {
+		if item != repo {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -0,0 +1,244 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"moments-go/config"
+	"moments-go/types"
+)
+
+// s3Store S3 兼容的对象存储（AWS S3、MinIO、Cloudflare R2 等），使用路径风格访问存储桶
+type s3Store struct {
+	client    *http.Client
+	endpoint  string
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	publicURL string
+}
+
+func newS3Store() (*s3Store, error) {
+	cfg := config.Cfg
+	if cfg.S3Endpoint == "" || cfg.S3Bucket == "" || cfg.S3AccessKey == "" || cfg.S3SecretKey == "" {
+		return nil, fmt.Errorf("使用 S3 存储需要设置 S3_ENDPOINT、S3_BUCKET、S3_ACCESS_KEY 和 S3_SECRET_KEY")
+	}
+
+	publicURL := cfg.S3PublicURL
+	if publicURL == "" {
+		publicURL = cfg.S3Endpoint + "/" + cfg.S3Bucket
+	}
+
+	return &s3Store{
+		client:    &http.Client{},
+		endpoint:  cfg.S3Endpoint,
+		region:    cfg.S3Region,
+		bucket:    cfg.S3Bucket,
+		accessKey: cfg.S3AccessKey,
+		secretKey: cfg.S3SecretKey,
+		publicURL: publicURL,
+	}, nil
+}
+
+func (s *s3Store) Name() string {
+	return "s3"
+}
+
+func (s *s3Store) Upload(file *types.MediaFile, timestamp string) (*types.UploadedFile, error) {
+	key := objectKey(file, timestamp)
+	if err := s.putObject(key, file); err != nil {
+		return nil, fmt.Errorf("上传文件 %s 到 S3 失败: %v", file.Name, err)
+	}
+	return &types.UploadedFile{Path: key, URL: objectURL(s.publicURL, key)}, nil
+}
+
+func (s *s3Store) Commit(files []types.UploadedFile) ([]types.UploadedFile, error) {
+	return files, nil
+}
+
+func (s *s3Store) Delete(file types.UploadedFile) error {
+	resp, err := s.do("DELETE", file.Path, "", nil, 0)
+	if err != nil {
+		return err
+	}
+	return checkS3Response(resp)
+}
+
+func (s *s3Store) Find(key string) (*types.UploadedFile, error) {
+	resp, err := s.do("HEAD", key, "", nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	// 没有 s3:ListBucket 权限时，不存在的对象返回 403 而不是 404，同样视为不存在，继续上传
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusForbidden {
+		resp.Body.Close()
+		return nil, nil
+	}
+	if err := checkS3Response(resp); err != nil {
+		return nil, err
+	}
+	return &types.UploadedFile{Path: key, URL: objectURL(s.publicURL, key)}, nil
+}
+
+func (s *s3Store) Extract(body string) []types.UploadedFile {
+	return extractObjects(body, s.publicURL, s.Name())
+}
+
+// putObject 上传对象，暂存的大文件以流的形式读取
+func (s *s3Store) putObject(key string, file *types.MediaFile) error {
+	reader, size, err := file.Reader()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	var body io.Reader = reader
+	if size <= 0 {
+		// S3 不支持分块传输的 PUT，大小未知时只能先读入内存
+		content, err := io.ReadAll(reader)
+		if err != nil {
+			return fmt.Errorf("读取文件失败: %v", err)
+		}
+		body, size = bytes.NewReader(content), int64(len(content))
+	}
+
+	resp, err := s.do("PUT", key, file.Type, body, size)
+	if err != nil {
+		return err
+	}
+	return checkS3Response(resp)
+}
+
+// do 发送签名后的请求
+func (s *s3Store) do(method, key, contentType string, body io.Reader, length int64) (*http.Response, error) {
+	target, err := url.Parse(s.endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("无效的对象地址: %v", err)
+	}
+	// 请求路径与签名使用同样的编码，否则 + = @ 等字符会导致签名不匹配
+	target.Path = strings.TrimSuffix(target.Path, "/") + "/" + s.bucket + "/" + key
+	target.RawPath = uriEncodePath(target.Path)
+
+	req, err := http.NewRequest(method, target.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %v", err)
+	}
+	if body != nil {
+		req.ContentLength = length
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	s.sign(req, time.Now().UTC())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("发送请求失败: %v", err)
+	}
+	return resp, nil
+}
+
+// sign 使用 AWS Signature Version 4 签名请求头中的所有字段。未设置 X-Amz-Content-Sha256 时请求体不参与签名，以便流式上传
+func (s *s3Store) sign(req *http.Request, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	payloadHash := req.Header.Get("X-Amz-Content-Sha256")
+	if payloadHash == "" {
+		payloadHash = "UNSIGNED-PAYLOAD"
+	}
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	var names []string
+	headers := make(map[string]string)
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		names = append(names, lower)
+		headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		uriEncodePath(req.URL.Path),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(sha256Sum([]byte(canonicalRequest))),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature))
+}
+
+// uriEncodePath 按 SigV4 的要求逐段编码路径：除字母、数字和 - _ . ~ 之外的字节都进行百分号编码，保留分隔的 /
+func uriEncodePath(path string) string {
+	const hexDigits = "0123456789ABCDEF"
+	var encoded strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/':
+			encoded.WriteByte(c)
+		default:
+			encoded.WriteByte('%')
+			encoded.WriteByte(hexDigits[c>>4])
+			encoded.WriteByte(hexDigits[c&15])
+		}
+	}
+	return encoded.String()
+}
+
+// checkS3Response 检查响应状态码
+func checkS3Response(resp *http.Response) error {
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("S3 请求失败，状态码: %d, 响应: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+func sha256Sum(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,238 @@
+package storage
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"moments-go/config"
+	"moments-go/github"
+	"moments-go/store"
+	"moments-go/types"
+)
+
+// MediaStore 媒体文件存储
+type MediaStore interface {
+	// Name 存储名称，记录在 UploadedFile.Store 中，用于之后删除
+	Name() string
+	// Upload 上传单个文件，可以并发调用
+	Upload(file *types.MediaFile, timestamp string) (*types.UploadedFile, error)
+	// Commit 本次上传的文件全部完成后调用，使文件生效并返回最终地址，顺序与传入顺序一致；不需要提交的存储原样返回
+	Commit(files []types.UploadedFile) ([]types.UploadedFile, error)
+	// Delete 删除已上传的文件
+	Delete(file types.UploadedFile) error
+	// Find 查找存储中已存在的文件，不存在时返回 nil
+	Find(path string) (*types.UploadedFile, error)
+	// Extract 提取动态内容中引用的、保存在该存储中的文件
+	Extract(body string) []types.UploadedFile
+}
+
+// stores 已启用的存储，存储名称 -> MediaStore
+var stores = map[string]MediaStore{
+	"github": githubStore{},
+}
+
+// Init 加载本地哈希索引和文件仓库切换记录，并根据配置初始化用到的存储
+func Init() error {
+	if err := openIndex(config.Cfg.DataDir); err != nil {
+		return err
+	}
+	if err := openRepoState(config.Cfg.DataDir); err != nil {
+		return err
+	}
+
+	for _, name := range config.Cfg.MediaStores {
+		if _, exists := stores[name]; exists {
+			continue
+		}
+
+		switch name {
+		case "s3":
+			mediaStore, err := newS3Store()
+			if err != nil {
+				return err
+			}
+			stores[name] = mediaStore
+		case "local":
+			mediaStore, err := newLocalStore()
+			if err != nil {
+				return err
+			}
+			stores[name] = mediaStore
+		}
+	}
+	return nil
+}
+
+// ForType 返回保存指定 MIME 类型文件的存储
+func ForType(mimeType string) MediaStore {
+	if name, exists := config.Cfg.MediaStores[mediaKind(mimeType)]; exists {
+		return stores[name]
+	}
+	if name, exists := config.Cfg.MediaStores[""]; exists {
+		return stores[name]
+	}
+	return stores["github"]
+}
+
+// mediaKind MIME 类型对应的媒体类型
+func mediaKind(mimeType string) string {
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		return "image"
+	case strings.HasPrefix(mimeType, "video/"):
+		return "video"
+	case strings.HasPrefix(mimeType, "audio/"):
+		return "audio"
+	default:
+		return "file"
+	}
+}
+
+// Delete 从保存文件的存储中删除文件
+func Delete(file types.UploadedFile) error {
+	name := storeName(file)
+	mediaStore, exists := stores[name]
+	if !exists {
+		return fmt.Errorf("存储 %s 未启用", name)
+	}
+	if err := mediaStore.Delete(file); err != nil {
+		return err
+	}
+	forgetFile(file)
+	return nil
+}
+
+// DeleteFiles 删除动态上传的文件，跳过仍被其他动态引用的文件（内容相同的文件会被多条动态共用）
+func DeleteFiles(issueNumber int, files []types.UploadedFile) {
+	for _, file := range files {
+		if referencedElsewhere(file, issueNumber) {
+			continue
+		}
+		if err := Delete(file); err != nil {
+			// 媒体文件可能已被手动删除，不影响动态的清理
+			log.Printf("删除动态 #%d 的媒体文件 %s 失败: %v", issueNumber, file.Path, err)
+		}
+	}
+}
+
+// DeleteReferencedFiles 删除动态内容中引用的所有媒体文件
+func DeleteReferencedFiles(issueNumber int, body string) {
+	for _, mediaStore := range stores {
+		files := mediaStore.Extract(body)
+		for i := range files {
+			files[i].Store = mediaStore.Name()
+		}
+		DeleteFiles(issueNumber, files)
+	}
+}
+
+// referencedElsewhere 检查本地索引中是否有其他动态引用了该文件
+func referencedElsewhere(file types.UploadedFile, issueNumber int) bool {
+	mediaStore, exists := stores[storeName(file)]
+	if !exists {
+		return false
+	}
+	return len(store.Query(func(moment *types.PublishedMoment) bool {
+		if moment.IssueNumber == issueNumber {
+			return false
+		}
+		for _, referenced := range mediaStore.Extract(moment.Content) {
+			referenced.Store = mediaStore.Name()
+			if sameFile(referenced, file) {
+				return true
+			}
+		}
+		return false
+	})) > 0
+}
+
+// storeName 保存文件的存储名称
+func storeName(file types.UploadedFile) string {
+	if file.Store == "" {
+		return "github"
+	}
+	return file.Store
+}
+
+// fileRepo 文件所在的文件仓库，未记录时为当前文件仓库
+func fileRepo(file types.UploadedFile) string {
+	if storeName(file) != "github" {
+		return ""
+	}
+	if file.Repo == "" {
+		return config.GetFileRepo()
+	}
+	return file.Repo
+}
+
+// sameFile 判断是否为存储中的同一个文件
+func sameFile(a, b types.UploadedFile) bool {
+	return storeName(a) == storeName(b) && a.Path == b.Path && a.Release == b.Release && fileRepo(a) == fileRepo(b)
+}
+
+// PurgeMoment 永久删除动态及其在各个存储中的媒体文件。先删除动态，成功后再删除文件，
+// 避免删除动态失败时动态仍在而媒体已经丢失
+func PurgeMoment(issue *types.GitHubIssueResponse) error {
+	if err := github.PurgeGitHubIssue(issue.NodeID); err != nil {
+		return fmt.Errorf("永久删除动态 #%d 失败: %v", issue.Number, err)
+	}
+	DeleteReferencedFiles(issue.Number, issue.Body)
+	return nil
+}
+
+// PurgeExpiredMoments 永久删除回收站中关闭时间超过 retentionDays 天的动态，返回已清理的编号
+func PurgeExpiredMoments(retentionDays int) ([]int, error) {
+	expired, err := github.ListExpiredMoments(retentionDays)
+	if err != nil {
+		return nil, err
+	}
+
+	var purged []int
+	for i := range expired {
+		if err := PurgeMoment(&expired[i]); err != nil {
+			return purged, err
+		}
+		purged = append(purged, expired[i].Number)
+	}
+	return purged, nil
+}
+
+// objectURL 将存储中的路径拼接到公开地址前缀后，路径逐段转义
+func objectURL(prefix, key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return prefix + "/" + strings.Join(segments, "/")
+}
+
+// extractObjects 提取内容中以公开地址前缀开头的文件，返回存储中的路径
+func extractObjects(body, prefix, name string) []types.UploadedFile {
+	if prefix == "" {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var files []types.UploadedFile
+	for _, field := range strings.FieldsFunc(body, func(r rune) bool {
+		return r == ' ' || r == '\n' || r == '"' || r == '\'' || r == '(' || r == ')' || r == '<' || r == '>'
+	}) {
+		if !strings.HasPrefix(field, prefix+"/") {
+			continue
+		}
+		key, err := url.PathUnescape(strings.TrimPrefix(field, prefix+"/"))
+		if err != nil || seen[key] {
+			continue
+		}
+		seen[key] = true
+		files = append(files, types.UploadedFile{Path: key, URL: field, Store: name})
+	}
+	return files
+}
+
+// objectKey 文件在存储中的路径，与文件仓库的路径保持一致
+func objectKey(file *types.MediaFile, timestamp string) string {
+	return github.MediaPath(file, timestamp)
+}
@@ -0,0 +1,272 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"moments-go/config"
+	"moments-go/github"
+	"moments-go/types"
+)
+
+// UploadState 单个文件的上传状态
+type UploadState int
+
+const (
+	UploadWaiting    UploadState = iota // 等待上传
+	UploadRunning                       // 正在上传
+	UploadRetrying                      // 上传失败，等待重试
+	UploadCommitting                    // 已上传，等待其他文件完成后一起提交到文件仓库
+	UploadDone                          // 上传完成
+	UploadReused                        // 存储中已有相同内容的文件，直接复用
+	UploadFailed                        // 多次尝试后仍然失败
+)
+
+// UploadProgress 单个文件的上传进度，通过 UploadMediaFiles 的 report 回调报告
+type UploadProgress struct {
+	Index   int // 文件在本次上传中的位置，封面缩略图排在所属媒体之前
+	File    *types.MediaFile
+	State   UploadState
+	Written int64 // 已上传的字节数
+	Size    int64 // 文件大小，未知时为 0
+	Attempt int   // 当前是第几次尝试
+	Err     error
+}
+
+// uploadResult 单个文件的上传结果
+type uploadResult struct {
+	file   types.UploadedFile
+	reused bool
+	err    error
+}
+
+// UploadMediaFiles 按媒体类型选择存储上传媒体文件（封面缩略图与媒体文件使用同一个存储），
+// 返回每个媒体的地址和本次新上传的文件（用于失败或撤回时清理）。
+// 内容相同的文件只上传一次：先查本地哈希索引，再查存储中是否已有按哈希命名的文件。
+// 文件由最多 UPLOAD_CONCURRENCY 个协程并发上传，失败的文件单独重试；部分文件最终失败时，
+// 已成功的文件仍会提交并记录到索引中，重新发布时直接复用。report 不为 nil 时报告每个文件的上传进度，可能被并发调用
+func UploadMediaFiles(mediaFiles []*types.MediaFile, report func(UploadProgress)) ([]github.UploadedMedia, []types.UploadedFile, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	if report == nil {
+		report = func(UploadProgress) {}
+	}
+
+	var files []*types.MediaFile
+	var fileStores []MediaStore
+	for _, file := range mediaFiles {
+		mediaStore := ForType(file.Type)
+		if file.Thumbnail != nil {
+			files = append(files, file.Thumbnail)
+			fileStores = append(fileStores, mediaStore)
+		}
+		files = append(files, file)
+		fileStores = append(fileStores, mediaStore)
+	}
+	for i, file := range files {
+		report(UploadProgress{Index: i, File: file, State: UploadWaiting})
+	}
+
+	repoMutex.RLock()
+	defer repoMutex.RUnlock()
+
+	results := make([]uploadResult, len(files))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for worker := 0; worker < min(config.Cfg.UploadConcurrency, len(files)); worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = uploadFile(fileStores[i], i, files[i], timestamp, report)
+			}
+		}()
+	}
+	for i := range files {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	// 每个存储提交一次本次上传成功的文件，Git Data API 在一次提交中添加所有文件
+	var order []MediaStore
+	for _, mediaStore := range fileStores {
+		if !containsStore(order, mediaStore) {
+			order = append(order, mediaStore)
+		}
+	}
+	ordered := make([]types.UploadedFile, len(files))
+	var uploaded []types.UploadedFile
+	for _, mediaStore := range order {
+		var positions []int
+		var staged []types.UploadedFile
+		for i, result := range results {
+			if fileStores[i] != mediaStore || result.err != nil {
+				continue
+			}
+			if result.reused {
+				ordered[i] = result.file
+				continue
+			}
+			positions = append(positions, i)
+			staged = append(staged, result.file)
+		}
+		if len(staged) == 0 {
+			continue
+		}
+
+		committed, err := mediaStore.Commit(staged)
+		for k, i := range positions {
+			if err != nil {
+				results[i].err = err
+				report(UploadProgress{Index: i, File: files[i], State: UploadFailed, Err: err})
+				continue
+			}
+			committed[k].Store = mediaStore.Name()
+			ordered[i] = committed[k]
+			uploaded = append(uploaded, committed[k])
+			if staged[k].Blob != "" {
+				report(UploadProgress{Index: i, File: files[i], State: UploadDone})
+			}
+		}
+	}
+	recordUploads(mediaFiles, files, ordered)
+
+	var failed []string
+	for i, result := range results {
+		if result.err != nil {
+			failed = append(failed, fmt.Sprintf("%s（%v）", files[i].Name, result.err))
+		}
+	}
+	if len(failed) > 0 {
+		return nil, uploaded, fmt.Errorf("%d 个文件上传失败: %s", len(failed), strings.Join(failed, "; "))
+	}
+
+	return github.MatchUploadedMedia(mediaFiles, files, ordered), uploaded, nil
+}
+
+// uploadFile 上传单个文件，失败时单独重试，最多尝试 MaxUploadAttempts 次
+func uploadFile(mediaStore MediaStore, position int, file *types.MediaFile, timestamp string, report func(UploadProgress)) uploadResult {
+	progress := UploadProgress{Index: position, File: file}
+	for attempt := 1; ; attempt++ {
+		progress.Attempt = attempt
+		result := tryUpload(mediaStore, file, timestamp, func(written, size int64) {
+			progress.State, progress.Written, progress.Size = UploadRunning, written, size
+			report(progress)
+		})
+
+		if result.err == nil {
+			switch {
+			case result.reused:
+				progress.State = UploadReused
+			case result.file.Blob != "":
+				progress.State = UploadCommitting
+			default:
+				progress.State = UploadDone
+			}
+			report(progress)
+			return result
+		}
+
+		progress.Err = result.err
+		if attempt >= config.MaxUploadAttempts {
+			progress.State = UploadFailed
+			report(progress)
+			return result
+		}
+		log.Printf("上传文件 %s 失败，第%d次尝试: %v，等待重试...", file.Name, attempt, result.err)
+		progress.State = UploadRetrying
+		report(progress)
+		time.Sleep(time.Duration(attempt) * time.Second)
+	}
+}
+
+// tryUpload 查找已上传的相同文件，不存在时上传，progress 报告已上传的字节数
+func tryUpload(mediaStore MediaStore, file *types.MediaFile, timestamp string, progress func(written, size int64)) uploadResult {
+	existing, err := findExisting(mediaStore, file, timestamp)
+	if err != nil {
+		return uploadResult{err: err}
+	}
+	if existing != nil {
+		return uploadResult{file: *existing, reused: true}
+	}
+
+	// 上传副本，读取内容时统计已上传的字节数
+	counted := *file
+	counted.Open = func() (io.ReadCloser, int64, error) {
+		reader, size, err := file.Reader()
+		if err != nil {
+			return nil, 0, err
+		}
+		progress(0, size)
+		return &countingReader{ReadCloser: reader, size: size, progress: progress}, size, nil
+	}
+
+	uploaded, err := mediaStore.Upload(&counted, timestamp)
+	if err != nil {
+		return uploadResult{err: err}
+	}
+	uploaded.Store = mediaStore.Name()
+	return uploadResult{file: *uploaded}
+}
+
+// findExisting 计算文件内容的哈希，查找已上传的相同文件
+func findExisting(mediaStore MediaStore, file *types.MediaFile, timestamp string) (*types.UploadedFile, error) {
+	if file.Hash == "" {
+		if file.Open != nil {
+			// 暂存的文件以流的形式计算哈希
+			hash, err := hashReader(file)
+			if err != nil {
+				return nil, fmt.Errorf("读取文件 %s 失败: %v", file.Name, err)
+			}
+			file.Hash = hash
+		} else {
+			file.Hash = hashContent(file.Content)
+		}
+	}
+
+	if existing, exists := lookupHash(file.Hash); exists {
+		return &existing, nil
+	}
+
+	existing, err := mediaStore.Find(objectKey(file, timestamp))
+	if err != nil {
+		return nil, fmt.Errorf("查找文件 %s 失败: %v", file.Name, err)
+	}
+	if existing != nil {
+		existing.Store = mediaStore.Name()
+	} else if file.Content == nil && file.Open == nil {
+		return nil, fmt.Errorf("文件 %s 已不在存储中", file.Name)
+	}
+	return existing, nil
+}
+
+// countingReader 统计已读取的字节数并报告上传进度
+type countingReader struct {
+	io.ReadCloser
+	read     int64
+	size     int64
+	progress func(read, size int64)
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		r.read += int64(n)
+		r.progress(r.read, r.size)
+	}
+	return n, err
+}
+
+func containsStore(stores []MediaStore, mediaStore MediaStore) bool {
+	for _, item := range stores {
+		if item == mediaStore {
+			return true
+		}
+	}
+	return false
+}
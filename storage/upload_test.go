@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"moments-go/config"
+	"moments-go/types"
+)
+
+// fakeStore 记录上传和提交的测试存储：前 parallel 次上传互相等待，全部开始后才继续，
+// 用于确认文件是并发上传的；failing 中的文件每次上传都失败
+type fakeStore struct {
+	parallel int
+	failing  map[string]bool
+
+	mutex     sync.Mutex
+	started   int
+	inFlight  int
+	maxFlight int
+	ready     chan struct{}
+	committed []string
+}
+
+func newFakeStore(parallel int, failing ...string) *fakeStore {
+	store := &fakeStore{parallel: parallel, failing: make(map[string]bool), ready: make(chan struct{})}
+	for _, name := range failing {
+		store.failing[name] = true
+	}
+	return store
+}
+
+func (s *fakeStore) Name() string {
+	return "fake"
+}
+
+func (s *fakeStore) Upload(file *types.MediaFile, timestamp string) (*types.UploadedFile, error) {
+	s.mutex.Lock()
+	s.started++
+	s.inFlight++
+	s.maxFlight = max(s.maxFlight, s.inFlight)
+	if s.started == s.parallel {
+		close(s.ready)
+	}
+	s.mutex.Unlock()
+	defer func() {
+		s.mutex.Lock()
+		s.inFlight--
+		s.mutex.Unlock()
+	}()
+
+	select {
+	case <-s.ready:
+	case <-time.After(2 * time.Second):
+		// 没有并发上传时不会等到其他文件，超时后继续，由测试检查最大并发数
+	}
+
+	if s.failing[file.Name] {
+		return nil, fmt.Errorf("模拟上传失败")
+	}
+	reader, _, err := file.Reader()
+	if err != nil {
+		return nil, err
+	}
+	reader.Close()
+	return &types.UploadedFile{Path: objectKey(file, timestamp)}, nil
+}
+
+func (s *fakeStore) Commit(files []types.UploadedFile) ([]types.UploadedFile, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	committed := make([]types.UploadedFile, len(files))
+	for i, file := range files {
+		s.committed = append(s.committed, file.Path)
+		committed[i] = types.UploadedFile{Path: file.Path, URL: "https://fake.example.com/" + file.Path}
+	}
+	return committed, nil
+}
+
+func (s *fakeStore) Delete(file types.UploadedFile) error {
+	return nil
+}
+
+func (s *fakeStore) Find(path string) (*types.UploadedFile, error) {
+	return nil, nil
+}
+
+func (s *fakeStore) Extract(body string) []types.UploadedFile {
+	return nil
+}
+
+// useFakeStore 使用测试存储保存所有文件，并使用空的内存索引，测试结束后恢复
+func useFakeStore(t *testing.T, store *fakeStore, concurrency int) {
+	t.Helper()
+	savedCfg := config.Cfg
+	savedIndex, savedPath := index, hashIndexPath
+	t.Cleanup(func() {
+		config.Cfg = savedCfg
+		index, hashIndexPath = savedIndex, savedPath
+		delete(stores, store.Name())
+	})
+
+	config.Cfg.MediaStores = map[string]string{"": store.Name()}
+	config.Cfg.MediaPathTemplate = config.DefaultMediaPathTemplate
+	config.Cfg.UploadConcurrency = concurrency
+	stores[store.Name()] = store
+	hashIndexPath = ""
+	index = hashIndexFile{Files: make(map[string]types.UploadedFile), Sources: make(map[string]*sourceEntry)}
+}
+
+func TestUploadMediaFilesPartialFailure(t *testing.T) {
+	store := newFakeStore(3, "bad.jpg")
+	useFakeStore(t, store, 3)
+
+	mediaFiles := []*types.MediaFile{
+		{Name: "first.jpg", Type: "image/jpeg", Content: []byte("first")},
+		{Name: "bad.jpg", Type: "image/jpeg", Content: []byte("bad")},
+		{Name: "second.jpg", Type: "image/jpeg", Content: []byte("second")},
+	}
+
+	var mutex sync.Mutex
+	final := make(map[string]UploadProgress)
+	_, uploaded, err := UploadMediaFiles(mediaFiles, func(progress UploadProgress) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		final[progress.File.Name] = progress
+	})
+
+	if err == nil || !strings.Contains(err.Error(), "1 个文件上传失败") || !strings.Contains(err.Error(), "bad.jpg") {
+		t.Fatalf("UploadMediaFiles() error = %v, want bad.jpg to fail", err)
+	}
+	if store.maxFlight != 3 {
+		t.Errorf("最多同时上传 %d 个文件，want 3", store.maxFlight)
+	}
+
+	// 失败的文件重试到最大次数，其他文件照常提交
+	if progress := final["bad.jpg"]; progress.State != UploadFailed || progress.Attempt != config.MaxUploadAttempts {
+		t.Errorf("bad.jpg state = %v, attempt = %d, want failed after %d attempts", progress.State, progress.Attempt, config.MaxUploadAttempts)
+	}
+	for _, name := range []string{"first.jpg", "second.jpg"} {
+		if progress := final[name]; progress.State != UploadDone {
+			t.Errorf("%s state = %v, want done", name, progress.State)
+		}
+	}
+
+	if len(uploaded) != 2 || len(store.committed) != 2 {
+		t.Fatalf("uploaded = %v, committed = %v, want 2 files", uploaded, store.committed)
+	}
+	for _, file := range uploaded {
+		if file.Store != "fake" || !strings.HasPrefix(file.URL, "https://fake.example.com/") {
+			t.Errorf("uploaded file = %+v", file)
+		}
+	}
+
+	// 成功的文件记录到索引中，重新发布时直接复用
+	for _, file := range mediaFiles {
+		_, recorded := lookupHash(file.Hash)
+		if want := file.Name != "bad.jpg"; recorded != want {
+			t.Errorf("%s recorded = %v, want %v", file.Name, recorded, want)
+		}
+	}
+}
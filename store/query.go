@@ -0,0 +1,115 @@
+package store
+
+import (
+	"strings"
+	"time"
+
+	"moments-go/github"
+	"moments-go/types"
+)
+
+// Page 一页动态
+type Page struct {
+	Moments  []*types.PublishedMoment
+	Page     int
+	LastPage int
+}
+
+// HasPrev 是否有上一页
+func (p *Page) HasPrev() bool {
+	return p.Page > 1
+}
+
+// HasNext 是否有下一页
+func (p *Page) HasNext() bool {
+	return p.Page < p.LastPage
+}
+
+// Paginate 对动态列表进行分页
+func Paginate(moments []*types.PublishedMoment, page, perPage int) *Page {
+	if perPage < 1 {
+		perPage = 10
+	}
+	lastPage := (len(moments) + perPage - 1) / perPage
+	if lastPage < 1 {
+		lastPage = 1
+	}
+	if page < 1 {
+		page = 1
+	}
+	if page > lastPage {
+		page = lastPage
+	}
+
+	start := (page - 1) * perPage
+	end := start + perPage
+	if end > len(moments) {
+		end = len(moments)
+	}
+
+	return &Page{
+		Moments:  moments[start:end],
+		Page:     page,
+		LastPage: lastPage,
+	}
+}
+
+// PageFromIssues 将 GitHub 接口返回的一页 Issue 转换为动态分页。不写入索引：
+// 索引只由同步和发布、编辑等操作更新，否则会与 moments.json 和同步位置不一致
+func PageFromIssues(result *github.IssuePage) *Page {
+	page := &Page{Page: result.Page, LastPage: result.LastPage()}
+	for i := range result.Issues {
+		page.Moments = append(page.Moments, FromIssue(&result.Issues[i]))
+	}
+	return page
+}
+
+// Search 在本地索引中搜索未删除的动态
+func Search(opts github.SearchOptions) *Page {
+	matched := Query(func(moment *types.PublishedMoment) bool {
+		return moment.State == "open" && matchesSearch(moment, opts)
+	})
+	return Paginate(matched, opts.Page, opts.PerPage)
+}
+
+// matchesSearch 判断动态是否满足搜索条件
+func matchesSearch(moment *types.PublishedMoment, opts github.SearchOptions) bool {
+	if opts.Keywords != "" {
+		text := strings.ToLower(moment.Title + "\n" + moment.Content)
+		for _, keyword := range strings.Fields(strings.ToLower(opts.Keywords)) {
+			if !strings.Contains(text, keyword) {
+				return false
+			}
+		}
+	}
+
+	if opts.Label != "" && !HasLabel(moment, opts.Label) {
+		return false
+	}
+
+	created := time.Unix(moment.CreatedAt, 0)
+	if opts.After != "" {
+		after, err := time.ParseInLocation("2006-01-02", opts.After, time.Local)
+		if err == nil && created.Before(after) {
+			return false
+		}
+	}
+	if opts.Before != "" {
+		before, err := time.ParseInLocation("2006-01-02", opts.Before, time.Local)
+		if err == nil && !created.Before(before) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// HasLabel 判断动态是否带有指定标签
+func HasLabel(moment *types.PublishedMoment, label string) bool {
+	for _, name := range moment.Labels {
+		if name == label {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,377 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"moments-go/github"
+	"moments-go/metadata"
+	"moments-go/types"
+)
+
+// indexFile 本地索引文件格式
+type indexFile struct {
+	LastSync string                   `json:"last_sync"` // 已同步到的最大 updated_at
+	Moments  []*types.PublishedMoment `json:"moments"`
+}
+
+var (
+	moments   = make(map[int]*types.PublishedMoment) // IssueNumber -> PublishedMoment
+	lastSync  string
+	syncedAt  time.Time
+	indexPath string
+	mutex     sync.RWMutex
+
+	// 保证同一时间只有一个同步在进行
+	syncMutex sync.Mutex
+)
+
+// mediaURLPattern 匹配动态内容中的图片和 HTML 媒体地址
+var mediaURLPattern = regexp.MustCompile(`!\[[^\]]*\]\(([^)\s]+)\)|(?:src|href)="([^"]+)"`)
+
+// Open 加载本地动态索引，文件不存在时创建空索引
+func Open(dataDir string) error {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return fmt.Errorf("创建数据目录失败: %v", err)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	indexPath = filepath.Join(dataDir, "moments.json")
+	data, err := os.ReadFile(indexPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取动态索引失败: %v", err)
+	}
+
+	var file indexFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("解析动态索引失败: %v", err)
+	}
+
+	lastSync = file.LastSync
+	for _, moment := range file.Moments {
+		moments[moment.IssueNumber] = moment
+	}
+
+	return nil
+}
+
+// save 将索引写入磁盘，调用方需持有锁
+func save() error {
+	if indexPath == "" {
+		return nil
+	}
+
+	file := indexFile{LastSync: lastSync}
+	for _, moment := range moments {
+		file.Moments = append(file.Moments, moment)
+	}
+	sort.Slice(file.Moments, func(i, j int) bool {
+		return file.Moments[i].IssueNumber < file.Moments[j].IssueNumber
+	})
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化动态索引失败: %v", err)
+	}
+
+	// 先写临时文件再重命名，避免写入中断导致索引损坏
+	tmpPath := indexPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("写入动态索引失败: %v", err)
+	}
+	return os.Rename(tmpPath, indexPath)
+}
+
+// Sync 增量同步：只拉取上次同步之后更新过的动态，返回更新的数量
+func Sync() (int, error) {
+	syncMutex.Lock()
+	defer syncMutex.Unlock()
+
+	mutex.RLock()
+	since := lastSync
+	mutex.RUnlock()
+
+	return syncSince(since)
+}
+
+// Resync 全量同步，并移除在 GitHub 上已不存在的动态
+func Resync() (int, error) {
+	syncMutex.Lock()
+	defer syncMutex.Unlock()
+
+	issues, err := github.ListAllIssues("all")
+	if err != nil {
+		return 0, err
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	moments = make(map[int]*types.PublishedMoment)
+	lastSync = ""
+	for i := range issues {
+		upsert(&issues[i])
+	}
+	syncedAt = time.Now()
+
+	return len(issues), save()
+}
+
+// Ready 索引是否完成过同步（包括之前运行时的同步），首次同步完成前索引不完整
+func Ready() bool {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return lastSync != "" || !syncedAt.IsZero()
+}
+
+// SyncIfStale 距离上次同步超过 maxAge 时执行增量同步
+func SyncIfStale(maxAge time.Duration) error {
+	mutex.RLock()
+	stale := time.Since(syncedAt) > maxAge
+	mutex.RUnlock()
+
+	if !stale {
+		return nil
+	}
+	_, err := Sync()
+	return err
+}
+
+// syncSince 拉取 since 之后更新过的全部动态并写入索引
+func syncSince(since string) (int, error) {
+	var updated []types.GitHubIssueResponse
+
+	page := 1
+	for page > 0 {
+		result, err := github.ListIssues(github.ListIssuesOptions{
+			State:     "all",
+			Sort:      "updated",
+			Direction: "asc",
+			Since:     since,
+			Page:      page,
+			PerPage:   100,
+		})
+		if err != nil {
+			return 0, err
+		}
+		updated = append(updated, result.Issues...)
+		page = result.Links.Next
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	count := 0
+	for i := range updated {
+		// since 包含等于的时间，上次同步时最后更新的动态会再次返回，没有变化的不计入
+		issue := &updated[i]
+		if existing, exists := moments[issue.Number]; exists && issue.UpdatedAt <= since && existing.UpdatedAt == parseTime(issue.UpdatedAt) {
+			continue
+		}
+		upsert(issue)
+		count++
+	}
+	syncedAt = time.Now()
+
+	if count == 0 {
+		return 0, nil
+	}
+	return count, save()
+}
+
+// StartSync 启动后台定时增量同步
+func StartSync(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if count, err := Sync(); err != nil {
+				log.Printf("同步动态索引失败: %v", err)
+			} else if count > 0 {
+				log.Printf("动态索引已同步 %d 条更新", count)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// upsert 写入一条动态并推进同步位置，调用方需持有写锁
+func upsert(issue *types.GitHubIssueResponse) *types.PublishedMoment {
+	moment := FromIssue(issue)
+	moments[moment.IssueNumber] = moment
+	if issue.UpdatedAt > lastSync {
+		lastSync = issue.UpdatedAt
+	}
+	return moment
+}
+
+// FromIssue 将 GitHub Issue 转换为索引记录
+func FromIssue(issue *types.GitHubIssueResponse) *types.PublishedMoment {
+	meta, content := metadata.Parse(issue.Body)
+	mediaURLs := ExtractMediaURLs(content)
+	if meta != nil {
+		mediaURLs = metadata.MediaURLs(meta)
+	}
+
+	return &types.PublishedMoment{
+		IssueID:     issue.ID,
+		NodeID:      issue.NodeID,
+		IssueNumber: issue.Number,
+		Title:       issue.Title,
+		Content:     content,
+		HTMLURL:     issue.HTMLURL,
+		State:       issue.State,
+		Labels:      issue.LabelNames(),
+		MediaURLs:   mediaURLs,
+		CreatedAt:   parseTime(issue.CreatedAt),
+		UpdatedAt:   parseTime(issue.UpdatedAt),
+		ClosedAt:    parseTime(issue.ClosedAt),
+		Metadata:    meta,
+	}
+}
+
+// ExtractMediaURLs 提取动态内容中引用的媒体地址
+func ExtractMediaURLs(body string) []string {
+	urls := []string{}
+	for _, match := range mediaURLPattern.FindAllStringSubmatch(body, -1) {
+		if match[1] != "" {
+			urls = append(urls, match[1])
+		} else if match[2] != "" {
+			urls = append(urls, match[2])
+		}
+	}
+	return urls
+}
+
+// parseTime 解析 GitHub 返回的时间，为空或无效时返回 0
+func parseTime(value string) int64 {
+	if value == "" {
+		return 0
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return 0
+	}
+	return t.Unix()
+}
+
+// PutIssue 将 GitHub 返回的 Issue 写入索引（发布、编辑、恢复后调用）
+func PutIssue(issue *types.GitHubIssueResponse) *types.PublishedMoment {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	moment := FromIssue(issue)
+	moments[moment.IssueNumber] = moment
+	if err := save(); err != nil {
+		log.Printf("保存动态索引失败: %v", err)
+	}
+	return copyMoment(moment)
+}
+
+// SetState 更新索引中动态的状态（open / closed）
+func SetState(issueNumber int, state string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	moment, exists := moments[issueNumber]
+	if !exists {
+		return
+	}
+	moment.State = state
+	moment.UpdatedAt = time.Now().Unix()
+	if state == "closed" {
+		moment.ClosedAt = moment.UpdatedAt
+	} else {
+		moment.ClosedAt = 0
+	}
+	if err := save(); err != nil {
+		log.Printf("保存动态索引失败: %v", err)
+	}
+}
+
+// Remove 从索引中移除已永久删除的动态
+func Remove(issueNumber int) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	delete(moments, issueNumber)
+	if err := save(); err != nil {
+		log.Printf("保存动态索引失败: %v", err)
+	}
+}
+
+// Get 从索引获取动态（返回副本）
+func Get(issueNumber int) (*types.PublishedMoment, bool) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	moment, exists := moments[issueNumber]
+	if !exists {
+		return nil, false
+	}
+	return copyMoment(moment), true
+}
+
+// GetOrFetch 从索引获取动态，不存在时从 GitHub 获取并写入索引
+func GetOrFetch(issueNumber int) (*types.PublishedMoment, error) {
+	if moment, exists := Get(issueNumber); exists {
+		return moment, nil
+	}
+
+	issue, err := github.GetGitHubIssue(issueNumber)
+	if err != nil {
+		return nil, err
+	}
+	if issue.IsPullRequest() {
+		return nil, fmt.Errorf("#%d 不是动态", issueNumber)
+	}
+
+	return PutIssue(issue), nil
+}
+
+// Query 返回满足条件的动态（副本），按创建时间倒序
+func Query(filter func(*types.PublishedMoment) bool) []*types.PublishedMoment {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	var result []*types.PublishedMoment
+	for _, moment := range moments {
+		if filter == nil || filter(moment) {
+			result = append(result, copyMoment(moment))
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].CreatedAt != result[j].CreatedAt {
+			return result[i].CreatedAt > result[j].CreatedAt
+		}
+		return result[i].IssueNumber > result[j].IssueNumber
+	})
+
+	return result
+}
+
+// copyMoment 复制动态，避免调用方修改索引内容
+func copyMoment(moment *types.PublishedMoment) *types.PublishedMoment {
+	copied := *moment
+	copied.Labels = append([]string(nil), moment.Labels...)
+	copied.MediaURLs = append([]string(nil), moment.MediaURLs...)
+	if moment.Metadata != nil {
+		meta := *moment.Metadata
+		copied.Metadata = &meta
+	}
+	return &copied
+}
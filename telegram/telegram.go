@@ -3,60 +3,41 @@ package telegram
 import (
 	"fmt"
 	"io"
+	"log"
 	"net/http"
+	"os"
+	"path/filepath"
 	"time"
 
 	"moments-go/config"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
+// spoolPattern 暂存文件的命名规则
+const spoolPattern = "moments-*"
+
+// NewBotAPI 使用配置的 Bot API 地址创建机器人
+func NewBotAPI() (*tgbotapi.BotAPI, error) {
+	return tgbotapi.NewBotAPIWithAPIEndpoint(config.Cfg.TelegramBotToken, config.Cfg.TelegramAPIURL+"/bot%s/%s")
+}
+
+// DownloadFile 下载文件并读入内存，只用于封面缩略图等小文件，媒体文件使用 SpoolFile
 func DownloadFile(bot *tgbotapi.BotAPI, fileID string) ([]byte, error) {
 	// 添加重试机制
 	maxRetries := 3
 	for attempt := 1; attempt <= maxRetries; attempt++ {
-		file, err := bot.GetFile(tgbotapi.FileConfig{FileID: fileID})
-		if err != nil {
-			if attempt == maxRetries {
-				return nil, fmt.Errorf("获取文件信息失败: %v", err)
-			}
-			fmt.Printf("获取文件信息失败，第%d次尝试: %v，等待重试...\n", attempt, err)
-			time.Sleep(time.Duration(attempt) * time.Second)
-			continue
-		}
-		
-		if file.FilePath == "" {
-			return nil, fmt.Errorf("无法获取文件路径")
-		}
-		
-		fileURL := fmt.Sprintf("https://api.telegram.org/file/bot%s/%s", config.Cfg.TelegramBotToken, file.FilePath)
-		
-		// 创建带超时的HTTP客户端
-		client := &http.Client{
-			Timeout: 30 * time.Second,
-		}
-		
-		resp, err := client.Get(fileURL)
+		body, _, err := OpenFile(bot, fileID)
 		if err != nil {
 			if attempt == maxRetries {
-				return nil, fmt.Errorf("下载文件失败: %v", err)
-			}
-			fmt.Printf("下载文件失败，第%d次尝试: %v，等待重试...\n", attempt, err)
-			time.Sleep(time.Duration(attempt) * time.Second)
-			continue
-		}
-		
-		defer resp.Body.Close()
-		
-		if resp.StatusCode != http.StatusOK {
-			if attempt == maxRetries {
-				return nil, fmt.Errorf("下载文件失败，状态码: %d", resp.StatusCode)
+				return nil, err
 			}
-			fmt.Printf("下载文件失败，状态码: %d，第%d次尝试，等待重试...\n", resp.StatusCode, attempt)
+			fmt.Printf("%v，第%d次尝试，等待重试...\n", err, attempt)
 			time.Sleep(time.Duration(attempt) * time.Second)
 			continue
 		}
 		
-		content, err := io.ReadAll(resp.Body)
+		content, err := io.ReadAll(body)
+		body.Close()
 		if err != nil {
 			if attempt == maxRetries {
 				return nil, fmt.Errorf("读取文件内容失败: %v", err)
@@ -72,6 +53,212 @@ func DownloadFile(bot *tgbotapi.BotAPI, fileID string) ([]byte, error) {
 	return nil, fmt.Errorf("下载文件失败，已重试%d次", maxRetries)
 }
 
+// OpenFile 打开 Telegram 文件的下载流，返回内容和文件大小（未知时为 -1），调用方负责关闭。
+// 大文件下载时间较长，只限制等待响应头的时间，不限制整体下载时间
+func OpenFile(bot *tgbotapi.BotAPI, fileID string) (io.ReadCloser, int64, error) {
+	filePath, err := getFilePath(bot, fileID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if localFile(filePath) {
+		file, err := os.Open(filePath)
+		if err != nil {
+			return nil, 0, fmt.Errorf("打开 Bot API 服务器上的文件失败: %v", err)
+		}
+		info, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return nil, 0, fmt.Errorf("读取 Bot API 服务器上的文件失败: %v", err)
+		}
+		return file, info.Size(), nil
+	}
+	
+	fileURL := fmt.Sprintf("%s/file/bot%s/%s", config.Cfg.TelegramAPIURL, config.Cfg.TelegramBotToken, filePath)
+	
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			ResponseHeaderTimeout: 30 * time.Second,
+		},
+	}
+	
+	resp, err := client.Get(fileURL)
+	if err != nil {
+		return nil, 0, fmt.Errorf("下载文件失败: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, 0, fmt.Errorf("下载文件失败，状态码: %d", resp.StatusCode)
+	}
+	
+	return resp.Body, resp.ContentLength, nil
+}
+
+// getFilePath 获取文件的下载路径，本地模式下为 Bot API 服务器磁盘上的绝对路径
+func getFilePath(bot *tgbotapi.BotAPI, fileID string) (string, error) {
+	file, err := bot.GetFile(tgbotapi.FileConfig{FileID: fileID})
+	if err != nil {
+		return "", fmt.Errorf("获取文件信息失败: %v", err)
+	}
+	if file.FilePath == "" {
+		return "", fmt.Errorf("无法获取文件路径")
+	}
+	return file.FilePath, nil
+}
+
+// localFile 本地模式下 Bot API 服务器返回的绝对路径可以直接读取，需要与服务器共享文件目录
+func localFile(filePath string) bool {
+	return config.Cfg.TelegramLocalMode && filepath.IsAbs(filePath)
+}
+
+// SpooledFile 下载到暂存目录的 Telegram 文件，上传时以流的形式读取，用完后调用 Remove 删除。
+// 本地模式下直接使用 Bot API 服务器保存的文件，不复制到暂存目录
+type SpooledFile struct {
+	Path string
+	Size int64
+
+	temporary bool // 是否为暂存目录中的文件，只有暂存文件会被删除
+}
+
+// Open 打开暂存文件，返回内容和文件大小，调用方负责关闭
+func (f *SpooledFile) Open() (io.ReadCloser, int64, error) {
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("打开暂存文件失败: %v", err)
+	}
+	return file, f.Size, nil
+}
+
+// Head 读取文件开头的最多 512 字节，用于识别文件类型
+func (f *SpooledFile) Head() ([]byte, error) {
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return nil, fmt.Errorf("打开暂存文件失败: %v", err)
+	}
+	defer file.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, fmt.Errorf("读取暂存文件失败: %v", err)
+	}
+	return head[:n], nil
+}
+
+// ReadAll 读取暂存文件的全部内容，用于需要在内存中处理的图片
+func (f *SpooledFile) ReadAll() ([]byte, error) {
+	return os.ReadFile(f.Path)
+}
+
+// Remove 删除暂存文件，Bot API 服务器保存的文件由服务器管理
+func (f *SpooledFile) Remove() {
+	if !f.temporary {
+		return
+	}
+	if err := os.Remove(f.Path); err != nil && !os.IsNotExist(err) {
+		log.Printf("删除暂存文件 %s 失败: %v", f.Path, err)
+	}
+}
+
+// InitSpool 创建暂存目录，并删除上次运行中断时遗留的暂存文件
+func InitSpool() error {
+	if err := os.MkdirAll(config.Cfg.SpoolDir, 0755); err != nil {
+		return fmt.Errorf("创建暂存目录失败: %v", err)
+	}
+	leftovers, err := filepath.Glob(filepath.Join(config.Cfg.SpoolDir, spoolPattern))
+	if err != nil {
+		return err
+	}
+	for _, path := range leftovers {
+		os.Remove(path)
+	}
+	return nil
+}
+
+// SpoolFile 将 Telegram 文件以流的形式下载到暂存目录，不在内存中保存完整内容。
+// progress 不为 nil 时在下载过程中报告已下载和总字节数（总大小未知时为 -1），下载中断时从头重试。
+// 本地模式下直接返回 Bot API 服务器磁盘上的文件
+func SpoolFile(bot *tgbotapi.BotAPI, fileID string, progress func(written, total int64)) (*SpooledFile, error) {
+	if config.Cfg.TelegramLocalMode {
+		filePath, err := getFilePath(bot, fileID)
+		if err != nil {
+			return nil, err
+		}
+		if localFile(filePath) {
+			info, err := os.Stat(filePath)
+			if err != nil {
+				return nil, fmt.Errorf("读取 Bot API 服务器上的文件失败: %v", err)
+			}
+			return &SpooledFile{Path: filePath, Size: info.Size()}, nil
+		}
+	}
+
+	file, err := os.CreateTemp(config.Cfg.SpoolDir, spoolPattern)
+	if err != nil {
+		return nil, fmt.Errorf("创建暂存文件失败: %v", err)
+	}
+
+	maxRetries := 3
+	for attempt := 1; ; attempt++ {
+		size, err := spoolOnce(bot, fileID, file, progress)
+		if err == nil {
+			if err := file.Close(); err != nil {
+				os.Remove(file.Name())
+				return nil, fmt.Errorf("写入暂存文件失败: %v", err)
+			}
+			return &SpooledFile{Path: file.Name(), Size: size, temporary: true}, nil
+		}
+		if attempt == maxRetries {
+			file.Close()
+			os.Remove(file.Name())
+			return nil, err
+		}
+		log.Printf("%v，第%d次尝试，等待重试...", err, attempt)
+		time.Sleep(time.Duration(attempt) * time.Second)
+	}
+}
+
+// spoolOnce 从头下载一次文件到暂存文件，返回文件大小
+func spoolOnce(bot *tgbotapi.BotAPI, fileID string, file *os.File, progress func(written, total int64)) (int64, error) {
+	if err := file.Truncate(0); err != nil {
+		return 0, fmt.Errorf("写入暂存文件失败: %v", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("写入暂存文件失败: %v", err)
+	}
+
+	body, total, err := OpenFile(bot, fileID)
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+
+	counter := &progressWriter{total: total, progress: progress}
+	size, err := io.Copy(io.MultiWriter(file, counter), body)
+	if err != nil {
+		return 0, fmt.Errorf("下载文件失败: %v", err)
+	}
+	if total > 0 && size != total {
+		return 0, fmt.Errorf("下载文件不完整: %d/%d 字节", size, total)
+	}
+	return size, nil
+}
+
+// progressWriter 统计已写入的字节数并报告下载进度
+type progressWriter struct {
+	written  int64
+	total    int64
+	progress func(written, total int64)
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.written += int64(len(p))
+	if w.progress != nil {
+		w.progress(w.written, w.total)
+	}
+	return len(p), nil
+}
+
 func ScheduleMediaPublish(bot *tgbotapi.BotAPI, chatID int64, callback func()) {
 	time.AfterFunc(time.Duration(config.WaitTime)*time.Second, callback)
 } 
@@ -1,5 +1,10 @@
 package types
 
+import (
+	"bytes"
+	"io"
+)
+
 type GitHubUploadResponse struct {
 	Content *struct {
 		DownloadURL string `json:"download_url"`
@@ -7,46 +12,269 @@ type GitHubUploadResponse struct {
 }
 
 type GitHubIssueResponse struct {
-	ID        int    `json:"id"`
-	Number    int    `json:"number"`
-	Title     string `json:"title"`
-	Body      string `json:"body"`
-	HTMLURL   string `json:"html_url"`
-	CreatedAt string `json:"created_at"`
+	ID          int                `json:"id"`
+	NodeID      string             `json:"node_id"`
+	Number      int                `json:"number"`
+	Title       string             `json:"title"`
+	Body        string             `json:"body"`
+	HTMLURL     string             `json:"html_url"`
+	State       string             `json:"state"`
+	Labels      []GitHubIssueLabel `json:"labels"`
+	CreatedAt   string             `json:"created_at"`
+	UpdatedAt   string             `json:"updated_at"`
+	ClosedAt    string             `json:"closed_at"`
+	PullRequest *struct {
+		URL string `json:"url"`
+	} `json:"pull_request,omitempty"`
+}
+
+// GitHubIssueLabel Issue 上附带的标签
+type GitHubIssueLabel struct {
+	Name string `json:"name"`
+}
+
+// LabelNames 返回 Issue 的标签名称列表
+func (i *GitHubIssueResponse) LabelNames() []string {
+	names := make([]string, 0, len(i.Labels))
+	for _, label := range i.Labels {
+		names = append(names, label.Name)
+	}
+	return names
+}
+
+// IsPullRequest 判断是否为 Pull Request（issues 接口会同时返回 PR）
+func (i *GitHubIssueResponse) IsPullRequest() bool {
+	return i.PullRequest != nil
+}
+
+// UploadedFile 已上传到文件仓库的文件
+type UploadedFile struct {
+	Path    string `json:"path"`
+	URL     string `json:"url"`
+	Release string `json:"release,omitempty"` // 上传为 Release 附件时的标签，此时 Path 为附件名
+	Repo    string `json:"repo,omitempty"`    // 所在的文件仓库，为空表示当前文件仓库
+	Store   string `json:"store,omitempty"`   // 保存文件的存储：github（默认）、s3、local
+	Blob    string `json:"-"`                 // 已创建、尚未提交到文件仓库的 blob SHA，提交后清空
 }
 
 type MediaFile struct {
 	Name    string
 	Content []byte
 	Type    string
+
+	// 不需要在内存中处理的文件下载到临时文件，上传时通过 Open 以流的形式读取，Size 为文件大小
+	Open func() (io.ReadCloser, int64, error)
+	Size int64
+	// 上传为文件仓库按月滚动的 Release 附件（大视频）
+	ReleaseAsset bool
+
+	// 以下为 Telegram 提供的元数据，用于生成动态内容
+	Duration  int        // 时长（秒）
+	Performer string     // 音频表演者
+	Title     string     // 音频标题
+	Loop      bool       // 以自动播放、循环、静音的视频显示（GIF 动画、视频贴纸）
+	Width     int        // 宽度（像素）
+	Height    int        // 高度（像素）
+	Alt       string     // 图片的替代文本
+	// 前端占位信息（仅图片）
+	DominantColor string // 主色，格式 #rrggbb
+	BlurHash      string
+	Thumbnail *MediaFile // 封面缩略图，随文件一起上传
+
+	// 内容去重：Hash 为文件内容的 SHA-256，设置后按哈希命名；
+	// SourceID 为来源的 Telegram FileUniqueID（及处理方式），用于在下载前识别重复文件
+	Hash     string
+	SourceID string
+}
+
+// Reader 打开文件内容：以流的形式读取的文件或内存中的内容，返回内容和大小，调用方负责关闭
+func (f *MediaFile) Reader() (io.ReadCloser, int64, error) {
+	if f.Open != nil {
+		reader, size, err := f.Open()
+		if err == nil && size <= 0 {
+			size = f.Size
+		}
+		return reader, size, err
+	}
+	return io.NopCloser(bytes.NewReader(f.Content)), int64(len(f.Content)), nil
 }
 
 type PendingMedia struct {
-	FileID  string
-	Type    string
-	Caption string
-	Labels  []string
+	FileID       string
+	FileUniqueID string // Telegram 文件的唯一标识，同一文件多次转发保持不变
+	Type         string
+	Caption      string
+	Labels       []string
+	FileName     string // 原始文件名（文件消息）
+	FileSize     int64  // Telegram 提供的文件大小
+	MimeType     string // Telegram 提供的 MIME 类型，仅作为识别失败时的参考
+
+	// 音频、视频等媒体的元数据
+	Duration    int
+	Performer   string
+	Title       string
+	Emoji       string // 贴纸对应的表情
+	ThumbFileID string // 封面缩略图
+	Width       int
+	Height      int
+
+	Watermark *bool // 是否添加水印，nil 表示按标签默认设置
+
+	Location *Location      // 打卡位置
+	Source   *MessageSource // 消息来源
+
+	MediaGroupID string          // 所在相册（media group）的标识，同一相册的媒体合并为一条动态
+	Group        []*PendingMedia // 同一相册中之后收到的媒体，按收到的顺序排列
+}
+
+// MessageSource 待发布内容对应的 Telegram 消息
+type MessageSource struct {
+	ChatID     int64  `json:"chat_id"`
+	MessageID  int    `json:"message_id"`
+	AuthorID   int64  `json:"author_id"`
+	Username   string `json:"username,omitempty"`
+	AuthorName string `json:"author_name,omitempty"`
+	Date       int64  `json:"date"` // 客户端发送时间（Unix 时间戳）
+}
+
+// Location 打卡位置（来自位置或地点消息）
+type Location struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Title     string  `json:"title,omitempty"`   // 地点名称
+	Address   string  `json:"address,omitempty"` // 地点地址
 }
 
-// PublishedMoment 已发布的动态
+// PublishedMoment 已发布的动态（本地索引中的记录）
 type PublishedMoment struct {
-	IssueID   int      `json:"issue_id"`
-	IssueNumber int    `json:"issue_number"`
-	Content   string   `json:"content"`
-	Labels    []string `json:"labels"`
-	MediaURLs []string `json:"media_urls"`
-	CreatedAt int64    `json:"created_at"`
-	UpdatedAt int64    `json:"updated_at"`
+	IssueID     int      `json:"issue_id"`
+	NodeID      string   `json:"node_id"`
+	IssueNumber int      `json:"issue_number"`
+	Title       string   `json:"title"`
+	Content     string   `json:"content"`
+	HTMLURL     string   `json:"html_url"`
+	State       string   `json:"state"`
+	Labels      []string `json:"labels"`
+	MediaURLs   []string `json:"media_urls"`
+	CreatedAt   int64    `json:"created_at"`
+	UpdatedAt   int64    `json:"updated_at"`
+	ClosedAt    int64    `json:"closed_at,omitempty"`
+
+	Metadata *MomentMetadata `json:"metadata,omitempty"` // 正文中的元数据块，Content 不包含该块
+}
+
+// MomentMetadata 动态正文中的机器可读元数据（HTML 注释中的 JSON），结构稳定，供前端和导出使用
+type MomentMetadata struct {
+	Version         int             `json:"version"`
+	PublishedAt     string          `json:"published_at"`          // RFC 3339
+	ClientTime      string          `json:"client_time,omitempty"` // Telegram 客户端发送消息的时间，RFC 3339
+	Source          *MetadataSource `json:"source,omitempty"`
+	Author          *MetadataAuthor `json:"author,omitempty"`
+	Location        *Location       `json:"location,omitempty"`
+	ContentWarnings []string        `json:"content_warnings,omitempty"`
+	Media           []MetadataMedia `json:"media"`
+}
+
+// MetadataSource 动态的来源消息
+type MetadataSource struct {
+	Client    string `json:"client"` // telegram
+	Type      string `json:"type"`   // text / photo / video / location 等
+	ChatID    int64  `json:"chat_id,omitempty"`
+	MessageID int    `json:"message_id,omitempty"`
+}
+
+// MetadataAuthor 动态的发布者
+type MetadataAuthor struct {
+	ID       int64  `json:"id,omitempty"`
+	Username string `json:"username,omitempty"`
+	Name     string `json:"name,omitempty"`
+}
+
+// MetadataMedia 动态中的媒体文件
+type MetadataMedia struct {
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url,omitempty"`
+	Name         string `json:"name"`
+	Type         string `json:"type"` // MIME 类型
+	Kind         string `json:"kind"` // image / video / audio / sticker / file
+	Width        int    `json:"width,omitempty"`
+	Height       int    `json:"height,omitempty"`
+	Duration     int    `json:"duration,omitempty"` // 秒
+
+	// 图片占位信息
+	DominantColor string `json:"dominant_color,omitempty"` // #rrggbb
+	BlurHash      string `json:"blurhash,omitempty"`
 }
 
 type Config struct {
 	TelegramBotToken string
 	TelegramUserID   int64
+	// Bot API 地址，可以指向自建的 telegram-bot-api 服务器
+	TelegramAPIURL string
+	// 自建服务器以 --local 模式运行：getFile 返回服务器磁盘上的绝对路径，文件大小上限为 2GB
+	TelegramLocalMode bool
 	GitHubSecret     string
 	GitHubFileRepo   string
 	GitHubUsername   string
 	GitHubRepo       string
 	GitHubUserAgent  string
+	// 上传媒体文件使用的接口：git（Git Data API，一次提交，默认）或 contents（每个文件一次提交）
+	GitHubUploadAPI string
+	// 超过该大小（字节）的视频上传为文件仓库按月滚动的 Release 附件，0 表示不使用 Release
+	ReleaseAssetThreshold int64
+	// 同时上传的文件数
+	UploadConcurrency int
+	// 文件仓库大小提醒阈值（MB），依次提醒
+	FileRepoWarnMB []int64
+	// 文件仓库超过该大小（MB）时自动切换到新仓库，0 表示不切换
+	FileRepoRolloverMB int64
+	// 上传文件的路径模板，支持 {{year}}、{{month}}、{{day}}、{{hash}}、{{ext}}、{{name}}、{{timestamp}}
+	MediaPathTemplate string
+	// 文件仓库中文件的访问地址模板，支持 {{owner}}、{{repo}}、{{branch}}、{{path}}，为空时使用 raw.githubusercontent.com
+	MediaURLTemplate string
+
+	// 媒体存储：github、s3 或 local，可以按媒体类型（image / video / audio / file）分别设置
+	MediaStores map[string]string
+	// S3 兼容对象存储
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+	S3PublicURL string
+	// 本地文件存储，通过机器人自带的 HTTP 服务访问
+	LocalStorageDir string
+	LocalPublicURL  string
+	HTTPListen      string
+
+	// 回收站保留天数，超过后永久删除；0 表示不自动清理
+	TrashRetentionDays int
+	// 发布后允许撤回的时间（秒）
+	UndoWindow int
+	// 本地数据目录（动态索引等）
+	DataDir string
+	// 从 Telegram 下载的文件暂存目录
+	SpoolDir string
+	// 打卡动态使用的标签
+	CheckinLabel string
+	// 标题和正文模板目录，时区用于模板中的时间
+	TemplateDir string
+	Timezone    string
+	// 是否在正文末尾附加机器可读的元数据块
+	MetadataBlock bool
+	// 上传前处理图片：去除 EXIF、缩放、重新编码并生成缩略图
+	ImageProcessing    bool
+	ImageMaxEdge       int
+	ImageQuality       int
+	ImageThumbnailEdge int
+	// 水印：文字或 PNG 图标，都为空时不启用
+	WatermarkText     string
+	WatermarkLogo     string
+	WatermarkFont     string
+	WatermarkPosition string
+	WatermarkOpacity  float64
+	WatermarkScale    float64
+	WatermarkLabels   []string // 默认添加水印的标签，为空时所有动态默认添加
 }
 
 var DefaultLabels = []string{